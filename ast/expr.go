@@ -3,6 +3,7 @@ package ast
 import "github.com/chase-compton/LOX_GO/scanner"
 
 type Expr interface {
+	Node
 	Accept(visitor ExprVisitor) (interface{}, error)
 }
 
@@ -19,9 +20,18 @@ type ExprVisitor interface {
 	VisitSetExpr(expr *Set) (interface{}, error)
 	VisitThisExpr(expr *This) (interface{}, error)
 	VisitSuperExpr(expr *Super) (interface{}, error)
+	VisitMatchExpr(expr *Match) (interface{}, error)
+	VisitListExpr(expr *List) (interface{}, error)
+	VisitDestructureAssignExpr(expr *DestructureAssign) (interface{}, error)
+	VisitTupleExpr(expr *Tuple) (interface{}, error)
+	VisitIndexExpr(expr *Index) (interface{}, error)
+	VisitIndexSetExpr(expr *IndexSet) (interface{}, error)
+	VisitSpawnExpr(expr *Spawn) (interface{}, error)
+	VisitAwaitExpr(expr *Await) (interface{}, error)
 }
 
 type Binary struct {
+	Span
 	Left     Expr
 	Operator scanner.Token
 	Right    Expr
@@ -32,6 +42,7 @@ func (b *Binary) Accept(visitor ExprVisitor) (interface{}, error) {
 }
 
 type Grouping struct {
+	Span
 	Expression Expr
 }
 
@@ -40,6 +51,7 @@ func (g *Grouping) Accept(visitor ExprVisitor) (interface{}, error) {
 }
 
 type Literal struct {
+	Span
 	Value interface{}
 }
 
@@ -48,6 +60,7 @@ func (l *Literal) Accept(visitor ExprVisitor) (interface{}, error) {
 }
 
 type Unary struct {
+	Span
 	Operator scanner.Token
 	Right    Expr
 }
@@ -57,6 +70,7 @@ func (u *Unary) Accept(visitor ExprVisitor) (interface{}, error) {
 }
 
 type Variable struct {
+	Span
 	Name scanner.Token
 }
 
@@ -65,6 +79,7 @@ func (v *Variable) Accept(visitor ExprVisitor) (interface{}, error) {
 }
 
 type Assign struct {
+	Span
 	Name  scanner.Token
 	Value Expr
 }
@@ -74,6 +89,7 @@ func (a *Assign) Accept(visitor ExprVisitor) (interface{}, error) {
 }
 
 type Logical struct {
+	Span
 	Left     Expr
 	Operator scanner.Token
 	Right    Expr
@@ -84,6 +100,7 @@ func (l *Logical) Accept(visitor ExprVisitor) (interface{}, error) {
 }
 
 type Call struct {
+	Span
 	Callee    Expr
 	Paren     scanner.Token
 	Arguments []Expr
@@ -94,6 +111,7 @@ func (c *Call) Accept(visitor ExprVisitor) (interface{}, error) {
 }
 
 type Get struct {
+	Span
 	Object Expr
 	Name   scanner.Token
 }
@@ -103,6 +121,7 @@ func (g *Get) Accept(visitor ExprVisitor) (interface{}, error) {
 }
 
 type Set struct {
+	Span
 	Object Expr
 	Name   scanner.Token
 	Value  Expr
@@ -113,6 +132,7 @@ func (s *Set) Accept(visitor ExprVisitor) (interface{}, error) {
 }
 
 type This struct {
+	Span
 	Keyword scanner.Token
 }
 
@@ -121,6 +141,7 @@ func (t *This) Accept(visitor ExprVisitor) (interface{}, error) {
 }
 
 type Super struct {
+	Span
 	Keyword scanner.Token
 	Method  scanner.Token
 }
@@ -128,3 +149,91 @@ type Super struct {
 func (s *Super) Accept(visitor ExprVisitor) (interface{}, error) {
 	return visitor.VisitSuperExpr(s)
 }
+
+type Match struct {
+	Span
+	Keyword scanner.Token
+	Subject Expr
+	Cases   []*MatchCase
+}
+
+func (m *Match) Accept(visitor ExprVisitor) (interface{}, error) {
+	return visitor.VisitMatchExpr(m)
+}
+
+type List struct {
+	Span
+	Bracket  scanner.Token
+	Elements []Expr
+}
+
+func (l *List) Accept(visitor ExprVisitor) (interface{}, error) {
+	return visitor.VisitListExpr(l)
+}
+
+// DestructureAssign is `[a, b] = value;`, assigning each element of a list to
+// an existing variable.
+type DestructureAssign struct {
+	Span
+	Bracket scanner.Token
+	Targets []*Variable
+	Value   Expr
+}
+
+func (d *DestructureAssign) Accept(visitor ExprVisitor) (interface{}, error) {
+	return visitor.VisitDestructureAssignExpr(d)
+}
+
+type Tuple struct {
+	Span
+	Paren    scanner.Token
+	Elements []Expr
+}
+
+func (t *Tuple) Accept(visitor ExprVisitor) (interface{}, error) {
+	return visitor.VisitTupleExpr(t)
+}
+
+type Index struct {
+	Span
+	Object  Expr
+	Bracket scanner.Token
+	Index   Expr
+}
+
+func (i *Index) Accept(visitor ExprVisitor) (interface{}, error) {
+	return visitor.VisitIndexExpr(i)
+}
+
+type IndexSet struct {
+	Span
+	Object  Expr
+	Bracket scanner.Token
+	Index   Expr
+	Value   Expr
+}
+
+func (i *IndexSet) Accept(visitor ExprVisitor) (interface{}, error) {
+	return visitor.VisitIndexSetExpr(i)
+}
+
+// Spawn is `spawn f(args)`, which runs the call on a new task.
+type Spawn struct {
+	Span
+	Keyword scanner.Token
+	Call    *Call
+}
+
+func (s *Spawn) Accept(visitor ExprVisitor) (interface{}, error) {
+	return visitor.VisitSpawnExpr(s)
+}
+
+type Await struct {
+	Span
+	Keyword scanner.Token
+	Value   Expr
+}
+
+func (a *Await) Accept(visitor ExprVisitor) (interface{}, error) {
+	return visitor.VisitAwaitExpr(a)
+}
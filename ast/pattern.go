@@ -0,0 +1,51 @@
+package ast
+
+import "github.com/chase-compton/LOX_GO/scanner"
+
+// Pattern is the left-hand side of a match case. Patterns are not evaluated
+// like expressions; the interpreter tests them against a value and binds any
+// names they introduce into the case's scope.
+type Pattern interface {
+	patternNode()
+}
+
+// LiteralPattern matches a value equal to a number, string, boolean or nil.
+type LiteralPattern struct {
+	Token scanner.Token
+	Value interface{}
+}
+
+// WildcardPattern, written `_`, matches anything and binds nothing.
+type WildcardPattern struct {
+	Token scanner.Token
+}
+
+// BindingPattern matches anything and binds the value to Name.
+type BindingPattern struct {
+	Name scanner.Token
+}
+
+// ClassPattern matches instances of Class (or one of its subclasses). Each
+// field pattern is tested against the instance field named by the matching
+// parameter of the class's initializer, so `Point(x, y)` reads the fields that
+// `init(x, y)` received.
+type ClassPattern struct {
+	Class  *Variable
+	Paren  scanner.Token
+	Fields []Pattern
+}
+
+func (p *LiteralPattern) patternNode()  {}
+func (p *WildcardPattern) patternNode() {}
+func (p *BindingPattern) patternNode()  {}
+func (p *ClassPattern) patternNode()    {}
+
+// MatchCase is a single `case` arm shared by MatchStmt and MatchExpr. Only one
+// of Body (statement form) and Value (expression form) is set.
+type MatchCase struct {
+	Keyword  scanner.Token
+	Patterns []Pattern
+	Guard    Expr
+	Body     Stmt
+	Value    Expr
+}
@@ -71,6 +71,42 @@ func (p *AstPrinter) VisitSuperExpr(expr *ast.Super) (interface{}, error) {
     return "super", nil
 }
 
+func (p *AstPrinter) VisitMatchExpr(expr *ast.Match) (interface{}, error) {
+	return p.parenthesize("match", expr.Subject)
+}
+
+func (p *AstPrinter) VisitListExpr(expr *ast.List) (interface{}, error) {
+	return p.parenthesize("list", expr.Elements...)
+}
+
+func (p *AstPrinter) VisitDestructureAssignExpr(expr *ast.DestructureAssign) (interface{}, error) {
+	names := make([]string, len(expr.Targets))
+	for n, target := range expr.Targets {
+		names[n] = target.Name.Lexeme
+	}
+	return p.parenthesize("assign ["+strings.Join(names, " ")+"]", expr.Value)
+}
+
+func (p *AstPrinter) VisitTupleExpr(expr *ast.Tuple) (interface{}, error) {
+	return p.parenthesize("tuple", expr.Elements...)
+}
+
+func (p *AstPrinter) VisitIndexExpr(expr *ast.Index) (interface{}, error) {
+	return p.parenthesize("index", expr.Object, expr.Index)
+}
+
+func (p *AstPrinter) VisitIndexSetExpr(expr *ast.IndexSet) (interface{}, error) {
+	return p.parenthesize("index-set", expr.Object, expr.Index, expr.Value)
+}
+
+func (p *AstPrinter) VisitSpawnExpr(expr *ast.Spawn) (interface{}, error) {
+	return p.parenthesize("spawn", expr.Call)
+}
+
+func (p *AstPrinter) VisitAwaitExpr(expr *ast.Await) (interface{}, error) {
+	return p.parenthesize("await", expr.Value)
+}
+
 func (p *AstPrinter) parenthesize(name string, exprs ...ast.Expr) (string, error) {
 	var builder strings.Builder
 
@@ -0,0 +1,45 @@
+package ast
+
+import (
+	"github.com/chase-compton/LOX_GO/errors"
+	"github.com/chase-compton/LOX_GO/scanner"
+)
+
+// Node is implemented by every Expr and Stmt through its embedded Span.
+type Node interface {
+	SourceSpan() Span
+	SetSpan(span Span)
+}
+
+// Span is the range of source a node was parsed from, from the start of its
+// first token to just past its last.
+type Span struct {
+	Start scanner.Position
+	End   scanner.Position
+}
+
+// NewSpan returns the span from the start of first to the end of last.
+func NewSpan(first, last scanner.Token) Span {
+	return Span{Start: first.Start(), End: last.End()}
+}
+
+func (s *Span) SourceSpan() Span {
+	return *s
+}
+
+func (s *Span) SetSpan(span Span) {
+	*s = span
+}
+
+// Diagnostic returns a diagnostic underlining the first line of the span.
+func (s Span) Diagnostic(code, message string) errors.Diagnostic {
+	length := 1
+	if s.End.Line == s.Start.Line {
+		length = max(s.End.Column-s.Start.Column, 1)
+	}
+	return errors.Diagnostic{
+		Code:    code,
+		Span:    errors.Span{Line: s.Start.Line, Column: s.Start.Column, Length: length},
+		Message: message,
+	}
+}
@@ -3,6 +3,7 @@ package ast
 import "github.com/chase-compton/LOX_GO/scanner"
 
 type Stmt interface {
+	Node
 	Accept(visitor StmtVisitor) (interface{}, error)
 }
 
@@ -16,10 +17,17 @@ type StmtVisitor interface {
 	VisitFunctionStmt(stmt *FunctionStmt) (interface{}, error)
 	VisitReturnStmt(stmt *ReturnStmt) (interface{}, error)
 	VisitClassStmt(stmt *ClassStmt) (interface{}, error)
+	VisitMatchStmt(stmt *MatchStmt) (interface{}, error)
+	VisitDestructureVarStmt(stmt *DestructureVarStmt) (interface{}, error)
+	VisitForInStmt(stmt *ForInStmt) (interface{}, error)
+	VisitSelectStmt(stmt *SelectStmt) (interface{}, error)
+	VisitAssertStmt(stmt *AssertStmt) (interface{}, error)
 }
 
 type VarStmt struct {
+	Span
 	Name        scanner.Token
+	Type        *TypeAnnotation // nil when not annotated
 	Initializer Expr
 }
 
@@ -28,6 +36,7 @@ func (s *VarStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 }
 
 type ExpressionStmt struct {
+	Span
 	Expression Expr
 }
 
@@ -36,6 +45,7 @@ func (s *ExpressionStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 }
 
 type PrintStmt struct {
+	Span
 	Expression Expr
 }
 
@@ -44,6 +54,7 @@ func (s *PrintStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 }
 
 type BlockStmt struct {
+    Span
     Statements []Stmt
 }
 
@@ -52,6 +63,7 @@ func (s *BlockStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 }
 
 type IfStmt struct {
+    Span
     Condition  Expr
     ThenBranch Stmt
     ElseBranch Stmt
@@ -62,6 +74,7 @@ func (s *IfStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 }
 
 type WhileStmt struct {
+    Span
     Condition Expr
     Body      Stmt
 }
@@ -71,9 +84,18 @@ func (s *WhileStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 }
 
 type FunctionStmt struct {
-    Name   scanner.Token
-    Params []scanner.Token
-    Body   []Stmt
+    Span
+    Name       scanner.Token
+    Params     []scanner.Token
+    ParamTypes []*TypeAnnotation // One per parameter, nil when not annotated
+    ReturnType *TypeAnnotation
+    Body       []Stmt
+    IsAsync    bool
+    Decorators []*Decorator
+    // Requires are checked before the body runs and Ensures after it
+    // returns, with the returned value bound to 'result'.
+    Requires []*Assertion
+    Ensures  []*Assertion
 }
 
 func (s *FunctionStmt) Accept(visitor StmtVisitor) (interface{}, error) {
@@ -81,8 +103,10 @@ func (s *FunctionStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 }
 
 type ReturnStmt struct {
-    Keyword scanner.Token
-    Value   Expr
+    Span
+    Keyword  scanner.Token
+    Value    Expr
+    TailCall bool // Set by the resolver when Value is a call in tail position
 }
 
 func (s *ReturnStmt) Accept(visitor StmtVisitor) (interface{}, error) {
@@ -90,11 +114,104 @@ func (s *ReturnStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 }
 
 type ClassStmt struct {
+    Span
     Name       scanner.Token
     Superclass *Variable // For inheritance
+    Fields     []*FieldDecl
     Methods    []*FunctionStmt
+    Decorators []*Decorator
+}
+
+// Decorator is an `@expression` line written above a function, method or
+// class declaration. Decorators are listed top to bottom as written.
+type Decorator struct {
+    At         scanner.Token
+    Expression Expr
 }
 
 func (s *ClassStmt) Accept(visitor StmtVisitor) (interface{}, error) {
     return visitor.VisitClassStmt(s)
 }
+
+type MatchStmt struct {
+    Span
+    Keyword scanner.Token
+    Subject Expr
+    Cases   []*MatchCase
+}
+
+func (s *MatchStmt) Accept(visitor StmtVisitor) (interface{}, error) {
+    return visitor.VisitMatchStmt(s)
+}
+
+// DestructureVarStmt declares several variables at once, either from the
+// elements of a list (`var [a, b, ...rest] = xs;`) or from the fields of an
+// instance (`var {name, age} = person;`).
+type DestructureVarStmt struct {
+    Span
+    Open        scanner.Token // '[' for lists, '{' for instances
+    Names       []scanner.Token
+    Rest        *scanner.Token
+    Initializer Expr
+}
+
+func (s *DestructureVarStmt) Accept(visitor StmtVisitor) (interface{}, error) {
+    return visitor.VisitDestructureVarStmt(s)
+}
+
+// ForInStmt is `for (var name in iterable) body`. Each iteration binds name in
+// a fresh scope, so closures created in the body capture that iteration's value.
+type ForInStmt struct {
+    Span
+    Name     scanner.Token
+    In       scanner.Token
+    Iterable Expr
+    Body     Stmt
+}
+
+func (s *ForInStmt) Accept(visitor StmtVisitor) (interface{}, error) {
+    return visitor.VisitForInStmt(s)
+}
+
+// SelectStmt waits until one of its channel operations can proceed and runs
+// that case's body, or runs Default immediately if none can.
+type SelectStmt struct {
+    Span
+    Keyword scanner.Token
+    Cases   []*SelectCase
+    Default Stmt
+}
+
+func (s *SelectStmt) Accept(visitor StmtVisitor) (interface{}, error) {
+    return visitor.VisitSelectStmt(s)
+}
+
+// SelectCase is either `case ch.send(value) => body` or
+// `case [var name =] ch.receive() => body`. Value is nil for receives.
+type SelectCase struct {
+    Keyword scanner.Token
+    Channel Expr
+    Value   Expr
+    Name    *scanner.Token
+    Body    Stmt
+}
+
+// AssertStmt is `assert condition;` or `assert condition, message;`.
+type AssertStmt struct {
+    Span
+    Assertion *Assertion
+}
+
+func (s *AssertStmt) Accept(visitor StmtVisitor) (interface{}, error) {
+    return visitor.VisitAssertStmt(s)
+}
+
+// Assertion is a condition that must hold at runtime: an assert statement or
+// a function's requires or ensures clause.
+type Assertion struct {
+    Keyword   scanner.Token
+    Condition Expr
+    Message   Expr // nil when there is none
+    // Source is the condition as written, for error messages.
+    Source string
+}
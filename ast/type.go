@@ -0,0 +1,19 @@
+package ast
+
+import "github.com/chase-compton/LOX_GO/scanner"
+
+// TypeAnnotation is a type written after a name, such as `num`, `Point` or
+// `num | nil`. The interpreter ignores annotations; only the type checker
+// reads them.
+type TypeAnnotation struct {
+	Span
+	// Alternatives are the names joined by '|', in the order written.
+	Alternatives []scanner.Token
+}
+
+// FieldDecl declares the type of a field in a class body, as in `x: num;`.
+type FieldDecl struct {
+	Span
+	Name scanner.Token
+	Type *TypeAnnotation
+}
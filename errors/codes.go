@@ -0,0 +1,55 @@
+package errors
+
+// Error codes are stable, so they can be searched for and documented. The
+// hundreds digit groups them: 0 for scanning, 1 for names, 2 for syntax, 3
+// for misplaced constructs the resolver rejects, 4 for runtime failures, 5
+// for faults in the interpreter itself and 6 for the type checker.
+// Warnings use the W prefix.
+const (
+	CodeUnexpectedCharacter = "E0001"
+	CodeUnterminatedString  = "E0002"
+	CodeInvalidNumber       = "E0003"
+
+	CodeDuplicateDeclaration = "E0101"
+	CodeUndefinedVariable    = "E0102"
+	CodeUndefinedProperty    = "E0103"
+	CodeSelfReference        = "E0104"
+
+	CodeSyntax                  = "E0200"
+	CodeInvalidAssignmentTarget = "E0201"
+	CodeTooManyArguments        = "E0202"
+
+	CodeInvalidPlacement = "E0300"
+	CodeSelfInheritance  = "E0301"
+
+	CodeRuntime       = "E0400"
+	CodeTypeMismatch  = "E0401"
+	CodeNotCallable   = "E0402"
+	CodeArityMismatch = "E0403"
+	CodeStackOverflow = "E0404"
+	CodeAssertion     = "E0405"
+
+	CodeInternal = "E0500"
+
+	CodeTypeError   = "E0600"
+	CodeUnknownType = "E0601"
+
+	CodeNonExhaustiveMatch = "W0001"
+	CodeUnusedVariable     = "W0002"
+	CodeUnusedParameter    = "W0003"
+	CodeUnreachableCode    = "W0004"
+	CodeShadowedVariable   = "W0005"
+	CodeConstantCondition  = "W0006"
+	CodeDeadAssignment     = "W0007"
+)
+
+// WarningCodes lists every warning, each of which is off until enabled.
+var WarningCodes = []string{
+	CodeNonExhaustiveMatch,
+	CodeUnusedVariable,
+	CodeUnusedParameter,
+	CodeUnreachableCode,
+	CodeShadowedVariable,
+	CodeConstantCondition,
+	CodeDeadAssignment,
+}
@@ -0,0 +1,177 @@
+package errors
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Span locates a diagnostic in the source. Line and Column are 1-based;
+// either is 0 when unknown. Length is how many characters to underline from
+// Column.
+type Span struct {
+	Line   int
+	Column int
+	Length int
+}
+
+// Diagnostic is one problem found in a program, located by its span so it
+// can be shown against the source.
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Span
+	Message string
+	// Help is an optional note, such as a did-you-mean suggestion.
+	Help string
+	// Runtime marks errors raised while the program ran, as opposed to
+	// those found before it started. Trace lists the calls that led to a
+	// runtime error, outermost first.
+	Runtime bool
+	Trace   []string
+}
+
+// Source is the program a diagnostic refers to, kept so rendering can name
+// its file and quote the offending line.
+type Source struct {
+	Path  string
+	lines []string
+}
+
+func NewSource(path, text string) *Source {
+	return &Source{Path: path, lines: strings.Split(text, "\n")}
+}
+
+// InternalError describes a failure inside the interpreter itself rather
+// than in the Lox program, such as a recovered Go panic.
+func InternalError(message string) Diagnostic {
+	return Diagnostic{Code: CodeInternal, Message: message, Runtime: true}
+}
+
+// ANSI escape sequences used when color is on.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[1;31m"
+	ansiYellow = "\x1b[1;33m"
+	ansiBlue   = "\x1b[1;34m"
+	ansiCyan   = "\x1b[1;36m"
+)
+
+// Render formats the diagnostic the way modern compilers do:
+//
+//	error[E0102]: Undefined variable 'cout'.
+//	 --> hello.lox:3:7
+//	  |
+//	3 | print cout;
+//	  |       ^^^^
+//	  = help: did you mean 'count'?
+//
+// A runtime error is preceded by its traceback. Source may be nil, in which
+// case no line is quoted.
+func (d Diagnostic) Render(source *Source, color bool) string {
+	paint := func(style, text string) string {
+		if !color {
+			return text
+		}
+		return style + text + ansiReset
+	}
+
+	severityStyle := ansiRed
+	if d.Severity == SeverityWarning {
+		severityStyle = ansiYellow
+	}
+
+	var b strings.Builder
+	if len(d.Trace) > 0 {
+		b.WriteString("Traceback (most recent call last):\n")
+		for _, entry := range d.Trace {
+			fmt.Fprintf(&b, "  %s\n", entry)
+		}
+	}
+	heading := d.Severity.String()
+	if d.Code != "" {
+		heading += "[" + d.Code + "]"
+	}
+	fmt.Fprintf(&b, "%s%s\n", paint(severityStyle, heading), paint(ansiBold, ": "+d.Message))
+
+	gutter := strings.Repeat(" ", len(strconv.Itoa(d.Line)))
+	if d.Line > 0 {
+		fmt.Fprintf(&b, "%s%s %s\n", gutter, paint(ansiBlue, "-->"), d.location(source))
+		if source != nil && d.Line <= len(source.lines) {
+			text := strings.TrimRight(source.lines[d.Line-1], "\r")
+			fmt.Fprintf(&b, "%s %s\n", gutter, paint(ansiBlue, "|"))
+			fmt.Fprintf(&b, "%s %s %s\n", paint(ansiBlue, strconv.Itoa(d.Line)), paint(ansiBlue, "|"), text)
+			if d.Column > 0 {
+				fmt.Fprintf(&b, "%s %s %s%s\n", gutter, paint(ansiBlue, "|"),
+					indentTo(text, d.Column), paint(severityStyle, underline(text, d.Column, d.Length)))
+			}
+		}
+	}
+	if d.Help != "" {
+		fmt.Fprintf(&b, "%s %s %s\n", gutter, paint(ansiBlue, "="), paint(ansiCyan, "help: ")+d.Help)
+	}
+	return b.String()
+}
+
+func (d Diagnostic) location(source *Source) string {
+	if source == nil || source.Path == "" {
+		if d.Column > 0 {
+			return fmt.Sprintf("line %d, column %d", d.Line, d.Column)
+		}
+		return fmt.Sprintf("line %d", d.Line)
+	}
+	if d.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d", source.Path, d.Line, d.Column)
+	}
+	return fmt.Sprintf("%s:%d", source.Path, d.Line)
+}
+
+// indentTo returns whitespace that lines up with column in text, keeping
+// tabs so the caret stays aligned however tabs are displayed.
+func indentTo(text string, column int) string {
+	var b strings.Builder
+	n := 1
+	for _, r := range text {
+		if n >= column {
+			break
+		}
+		if r == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+		n++
+	}
+	for ; n < column; n++ {
+		b.WriteRune(' ')
+	}
+	return b.String()
+}
+
+// underline returns the carets for length characters from column, cut off at
+// the end of the line but always at least one.
+func underline(text string, column, length int) string {
+	available := len([]rune(text)) - column + 1
+	if length > available {
+		length = available
+	}
+	if length < 1 {
+		length = 1
+	}
+	return strings.Repeat("^", length)
+}
@@ -1,36 +1,110 @@
 package errors
 
 import (
-	"fmt"
+	"io"
 	"os"
+	"sync"
 )
 
-var HadError = false
-var HadRuntimeError = false
+// Diagnostics receives the errors and warnings found while a program is
+// scanned, parsed, resolved and run. Each stage reports to the sink it was
+// constructed with, so separate runs don't share any state.
+type Diagnostics interface {
+	Report(d Diagnostic)
+}
+
+// Collector is a Diagnostics that keeps everything reported, in order. It is
+// safe for concurrent use by tasks running in parallel.
+type Collector struct {
+	mu          sync.Mutex
+	diagnostics []Diagnostic
+}
+
+func NewCollector() *Collector {
+	return &Collector{}
+}
 
-func Error(line int, message string) {
-	report(line, "", message)
+func (c *Collector) Report(d Diagnostic) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.diagnostics = append(c.diagnostics, d)
 }
 
-func ReportParseError(line int, lexeme string, isAtEnd bool, message string) {
-	if isAtEnd {
-		report(line, " at end", message)
-	} else {
-		report(line, fmt.Sprintf(" at '%s'", lexeme), message)
+// Diagnostics returns everything reported so far.
+func (c *Collector) Diagnostics() []Diagnostic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Diagnostic(nil), c.diagnostics...)
+}
+
+// HasErrors reports whether an error was found before the program ran.
+// Errors stop the program from running; warnings don't.
+func (c *Collector) HasErrors() bool {
+	return c.has(func(d Diagnostic) bool { return d.Severity == SeverityError && !d.Runtime })
+}
+
+// HasRuntimeErrors reports whether the program stopped with an error.
+func (c *Collector) HasRuntimeErrors() bool {
+	return c.has(func(d Diagnostic) bool { return d.Runtime })
+}
+
+func (c *Collector) has(match func(Diagnostic) bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, d := range c.diagnostics {
+		if match(d) {
+			return true
+		}
 	}
+	return false
+}
+
+// Reset forgets everything reported, as the REPL does between lines.
+func (c *Collector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.diagnostics = nil
+}
+
+// Printer is a Collector that also renders each diagnostic to a writer as
+// it is reported.
+type Printer struct {
+	Collector
+	w      io.Writer
+	color  bool
+	source *Source
 }
 
-func ReportRuntimeError(line int, message string) {
-	fmt.Fprintf(os.Stderr, "[line %d] Runtime Error: %s\n", line, message)
-	HadRuntimeError = true
+// NewPrinter returns a Printer writing to w, in color when w is a terminal.
+func NewPrinter(w io.Writer) *Printer {
+	return &Printer{w: w, color: useColor(w)}
 }
 
-func ReportResolverError(message string) {
-	fmt.Fprintf(os.Stderr, "Resolver Error: %s\n", message)
-	HadError = true
+// SetSource records the program being run so diagnostics can name its file
+// and quote the offending line.
+func (p *Printer) SetSource(path, text string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.source = NewSource(path, text)
 }
 
-func report(line int, where string, message string) {
-	fmt.Fprintf(os.Stderr, "[line %d] Error%s: %s\n", line, where, message)
-	HadError = true
+func (p *Printer) Report(d Diagnostic) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.diagnostics = append(p.diagnostics, d)
+	io.WriteString(p.w, d.Render(p.source, p.color))
+}
+
+// useColor reports whether w is a terminal that should get colored output.
+// Setting NO_COLOR turns color off.
+func useColor(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
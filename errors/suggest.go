@@ -0,0 +1,47 @@
+package errors
+
+import "fmt"
+
+// DidYouMean returns a help note suggesting the candidate closest to name,
+// or "" when none is close enough to be a likely typo.
+func DidYouMean(name string, candidates []string) string {
+	// Allow about one typo for every three characters.
+	threshold := len([]rune(name))/3 + 1
+	best, bestDistance := "", threshold+1
+	for _, candidate := range candidates {
+		if candidate == name {
+			continue
+		}
+		distance := editDistance(name, candidate)
+		// Break ties alphabetically so the suggestion is deterministic.
+		if distance < bestDistance || (distance == bestDistance && candidate < best) {
+			best, bestDistance = candidate, distance
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf("did you mean '%s'?", best)
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
@@ -0,0 +1,67 @@
+package errors
+
+import "strings"
+
+// ignoreDirective starts a comment that silences warnings.
+const ignoreDirective = "lox:ignore"
+
+// Suppressions records `// lox:ignore` comments. One trailing code silences
+// warnings on its own line; one on a line by itself silences those on the
+// line after, so it can sit just above the offending code. It lists the
+// codes to silence, or none to silence every warning.
+type Suppressions map[int]Suppression
+
+// Suppression is a single `// lox:ignore` comment.
+type Suppression struct {
+	Codes []string
+	// Alone is set when the comment is on a line by itself.
+	Alone bool
+}
+
+// ParseIgnore reads the text of a comment, without its leading slashes, as
+// an ignore directive, returning the codes it lists.
+func ParseIgnore(comment string) ([]string, bool) {
+	comment = strings.TrimSpace(comment)
+	if !strings.HasPrefix(comment, ignoreDirective) {
+		return nil, false
+	}
+	rest := comment[len(ignoreDirective):]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return nil, false
+	}
+	codes := strings.FieldsFunc(rest, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ','
+	})
+	return codes, true
+}
+
+// Add records a directive on line. alone says whether it is the only thing
+// on the line.
+func (s Suppressions) Add(line int, codes []string, alone bool) {
+	s[line] = Suppression{Codes: codes, Alone: alone}
+}
+
+// Suppresses reports whether a directive silences d. Errors can't be
+// silenced.
+func (s Suppressions) Suppresses(d Diagnostic) bool {
+	if d.Severity != SeverityWarning {
+		return false
+	}
+	if suppression, ok := s[d.Line-1]; ok && suppression.Alone && suppression.covers(d.Code) {
+		return true
+	}
+	suppression, ok := s[d.Line]
+	return ok && suppression.covers(d.Code)
+}
+
+func (s Suppression) covers(code string) bool {
+	if len(s.Codes) == 0 {
+		return true
+	}
+	for _, c := range s.Codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
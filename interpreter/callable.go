@@ -1,6 +1,19 @@
 package interpreter
 
+// VariadicArity is returned by Arity for callables that accept any number of
+// arguments and check them themselves.
+const VariadicArity = -1
+
 type Callable interface {
     Arity() int
     Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error)
 }
+
+// Method is a method a class declares: a LoxFunction, or a NativeFunction
+// for a class implemented in Go. Binding it to an instance gives the
+// callable that `instance.name` evaluates to, so Lox and native methods can
+// be mixed in one inheritance chain.
+type Method interface {
+    Arity() int
+    Bind(instance *LoxInstance) Callable
+}
@@ -0,0 +1,82 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/chase-compton/LOX_GO/ast"
+	"github.com/chase-compton/LOX_GO/errors"
+)
+
+// SetContracts turns the checking of assert statements and requires and
+// ensures clauses on or off. They are checked by default; with checking off,
+// their conditions aren't even evaluated.
+func (i *Interpreter) SetContracts(enabled bool) {
+	i.contracts = enabled
+}
+
+func (i *Interpreter) VisitAssertStmt(stmt *ast.AssertStmt) (interface{}, error) {
+	if !i.contracts {
+		return nil, nil
+	}
+	return nil, i.checkAssertion(stmt.Assertion, "Assertion")
+}
+
+// checkRequires checks a function's preconditions in environment, which
+// holds its arguments.
+func (i *Interpreter) checkRequires(function *LoxFunction, environment *Environment) error {
+	if !i.contracts || len(function.Declaration.Requires) == 0 {
+		return nil
+	}
+	return i.checkClauses(function.Declaration.Requires, environment,
+		fmt.Sprintf("Precondition of '%s'", function.Declaration.Name.Lexeme))
+}
+
+// checkEnsures checks a function's postconditions once it has returned
+// result, which they see as 'result'.
+func (i *Interpreter) checkEnsures(function *LoxFunction, environment *Environment, result interface{}) error {
+	if !i.contracts || len(function.Declaration.Ensures) == 0 {
+		return nil
+	}
+	environment = NewEnvironment(environment)
+	environment.Define("result", result)
+	return i.checkClauses(function.Declaration.Ensures, environment,
+		fmt.Sprintf("Postcondition of '%s'", function.Declaration.Name.Lexeme))
+}
+
+func (i *Interpreter) checkClauses(clauses []*ast.Assertion, environment *Environment, kind string) error {
+	previous := i.environment
+	i.environment = environment
+	defer func() {
+		i.environment = previous
+	}()
+
+	for _, clause := range clauses {
+		if err := i.checkAssertion(clause, kind); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkAssertion evaluates an assertion's condition, failing with its source
+// text and message when the condition is falsey. kind names what failed.
+func (i *Interpreter) checkAssertion(assertion *ast.Assertion, kind string) error {
+	condition, err := i.evaluate(assertion.Condition)
+	if err != nil || isTruthy(condition) {
+		return err
+	}
+
+	message := fmt.Sprintf("%s failed: %s.", kind, assertion.Source)
+	if assertion.Message != nil {
+		value, err := i.evaluate(assertion.Message)
+		if err != nil {
+			return err
+		}
+		message = fmt.Sprintf("%s failed: %s (%s).", kind, assertion.Source, stringify(value))
+	}
+	return &RuntimeError{
+		Token:   assertion.Keyword,
+		Message: message,
+		Code:    errors.CodeAssertion,
+	}
+}
@@ -0,0 +1,136 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/chase-compton/LOX_GO/ast"
+	"github.com/chase-compton/LOX_GO/scanner"
+)
+
+// decorator is an evaluated `@expression`, kept with its '@' token so errors
+// raised while applying it point at the decorator line.
+type decorator struct {
+	at       scanner.Token
+	callable Callable
+}
+
+// evaluateDecorators evaluates decorator expressions top to bottom, as they
+// are written, and checks that each one can be applied.
+func (i *Interpreter) evaluateDecorators(decorators []*ast.Decorator) ([]decorator, error) {
+	var evaluated []decorator
+	for _, d := range decorators {
+		value, err := i.evaluate(d.Expression)
+		if err != nil {
+			return nil, err
+		}
+		callable, ok := value.(Callable)
+		if !ok || (callable.Arity() != 1 && callable.Arity() != VariadicArity) {
+			return nil, &RuntimeError{
+				Token:   d.At,
+				Message: "Decorator must be a function that takes one argument.",
+			}
+		}
+		evaluated = append(evaluated, decorator{at: d.At, callable: callable})
+	}
+	return evaluated, nil
+}
+
+// decorate applies decorators to value bottom-up, so the decorator written
+// nearest the declaration wraps it first.
+func (i *Interpreter) decorate(decorators []decorator, value interface{}) (interface{}, error) {
+	for n := len(decorators) - 1; n >= 0; n-- {
+		var err error
+		value, err = i.call(decorators[n].at, decorators[n].callable, []interface{}{value})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// decoratedMethod is a method in a class's Methods as its decorators left
+// it. They run once, when the class is declared, and are given the method
+// as an unboundMethod, which takes the instance it runs on as its first
+// argument. Binding the result to an instance passes the instance to it the
+// same way, so wrappers work however and whenever they call the method.
+type decoratedMethod struct {
+	method *LoxFunction
+	value  interface{}
+}
+
+// newDecoratedMethod checks that value, what a method's decorators returned,
+// can be called with the instance first, unless it isn't meant to be called
+// at all.
+func newDecoratedMethod(at scanner.Token, method *LoxFunction, value interface{}) (*decoratedMethod, error) {
+	if callable, ok := value.(Callable); ok && callable.Arity() == 0 {
+		return nil, &RuntimeError{
+			Token:   at,
+			Message: fmt.Sprintf("Decorated method '%s' must take the instance as its first parameter.", method.Declaration.Name.Lexeme),
+		}
+	}
+	return &decoratedMethod{method: method, value: value}, nil
+}
+
+func (m *decoratedMethod) Arity() int {
+	callable, ok := m.value.(Callable)
+	if !ok {
+		return 0
+	}
+	if callable.Arity() == VariadicArity {
+		return VariadicArity
+	}
+	return callable.Arity() - 1
+}
+
+func (m *decoratedMethod) Bind(instance *LoxInstance) Callable {
+	callable, _ := m.value.(Callable)
+	return &boundMethod{instance: instance, callable: callable, arity: m.Arity()}
+}
+
+func (m *decoratedMethod) String() string {
+	return stringify(m.value)
+}
+
+// unboundMethod is a method as its decorators are given it: calling it
+// with an instance and the method's arguments calls the method on that
+// instance.
+type unboundMethod struct {
+	method *LoxFunction
+}
+
+func (m *unboundMethod) Arity() int {
+	return m.method.Arity() + 1
+}
+
+func (m *unboundMethod) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	instance, ok := arguments[0].(*LoxInstance)
+	if !ok || !instance.Class.isSubclassOf(m.method.class) {
+		return nil, fmt.Errorf("Method '%s' must be called with an instance of '%s' first.",
+			m.method.Declaration.Name.Lexeme, m.method.class.Name)
+	}
+	return m.method.bind(instance).Call(interpreter, arguments[1:])
+}
+
+func (m *unboundMethod) String() string {
+	return fmt.Sprintf("<fn %s>", m.method.Declaration.Name.Lexeme)
+}
+
+// boundMethod is a decorated method bound to an instance, which it passes
+// to the decorated value ahead of the arguments.
+type boundMethod struct {
+	instance *LoxInstance
+	callable Callable
+	arity    int
+}
+
+func (b *boundMethod) Arity() int {
+	return b.arity
+}
+
+func (b *boundMethod) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	return b.callable.Call(interpreter, append([]interface{}{b.instance}, arguments...))
+}
+
+func (b *boundMethod) String() string {
+	return stringify(b.callable)
+}
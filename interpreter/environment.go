@@ -2,12 +2,17 @@ package interpreter
 
 import (
 	"fmt"
+	"sync"
 
+	"github.com/chase-compton/LOX_GO/errors"
 	"github.com/chase-compton/LOX_GO/scanner"
 )
 
+// Environment holds the variables of one scope. Its methods are safe to call
+// from concurrently running tasks; see task.go for what that guarantees.
 type Environment struct {
 	Enclosing *Environment
+	mu        sync.RWMutex
 	values    map[string]interface{}
 }
 
@@ -19,41 +24,75 @@ func NewEnvironment(enclosing *Environment) *Environment {
 }
 
 func (env *Environment) Define(name string, value interface{}) {
+	env.mu.Lock()
+	defer env.mu.Unlock()
 	env.values[name] = value
 }
 
+func (env *Environment) lookup(name string) (interface{}, bool) {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+	value, ok := env.values[name]
+	return value, ok
+}
+
+// store overwrites an existing variable and reports whether it was there.
+func (env *Environment) store(name string, value interface{}) bool {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	if _, ok := env.values[name]; !ok {
+		return false
+	}
+	env.values[name] = value
+	return true
+}
+
 func (env *Environment) Get(name scanner.Token) (interface{}, error) {
-	if value, ok := env.values[name.Lexeme]; ok {
-		return value, nil
+	for e := env; e != nil; e = e.Enclosing {
+		if value, ok := e.lookup(name.Lexeme); ok {
+			return value, nil
+		}
 	}
+	return nil, env.undefinedVariable(name)
+}
 
-	if env.Enclosing != nil {
-		return env.Enclosing.Get(name)
+func (env *Environment) Assign(name scanner.Token, value interface{}) error {
+	for e := env; e != nil; e = e.Enclosing {
+		if e.store(name.Lexeme, value) {
+			return nil
+		}
 	}
+	return env.undefinedVariable(name)
+}
 
-	return nil, &RuntimeError{
+// undefinedVariable reports name as undefined, suggesting the closest
+// variable visible from env.
+func (env *Environment) undefinedVariable(name scanner.Token) *RuntimeError {
+	var names []string
+	for e := env; e != nil; e = e.Enclosing {
+		names = append(names, e.names()...)
+	}
+	return &RuntimeError{
 		Token:   name,
 		Message: fmt.Sprintf("Undefined variable '%s'.", name.Lexeme),
+		Code:    errors.CodeUndefinedVariable,
+		Help:    errors.DidYouMean(name.Lexeme, names),
 	}
 }
 
-func (e *Environment) Assign(name scanner.Token, value interface{}) error {
-	if _, ok := e.values[name.Lexeme]; ok {
-		e.values[name.Lexeme] = value
-		return nil
-	} else if e.Enclosing != nil {
-		return e.Enclosing.Assign(name, value)
-	} else {
-		return &RuntimeError{
-			Token:   name,
-			Message: fmt.Sprintf("Undefined variable '%s'.", name.Lexeme),
-		}
+func (env *Environment) names() []string {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+	names := make([]string, 0, len(env.values))
+	for name := range env.values {
+		names = append(names, name)
 	}
+	return names
 }
 
 func (env *Environment) GetAt(distance int, name string) (interface{}, error) {
 	ancestor := env.ancestor(distance)
-	if value, ok := ancestor.values[name]; ok {
+	if value, ok := ancestor.lookup(name); ok {
 		return value, nil
 	}
 	return nil, fmt.Errorf("Undefined variable '%s'.", name)
@@ -61,8 +100,7 @@ func (env *Environment) GetAt(distance int, name string) (interface{}, error) {
 
 func (env *Environment) AssignAt(distance int, name scanner.Token, value interface{}) error {
 	ancestor := env.ancestor(distance)
-	if _, ok := ancestor.values[name.Lexeme]; ok {
-		ancestor.values[name.Lexeme] = value
+	if ancestor.store(name.Lexeme, value) {
 		return nil
 	}
 	return fmt.Errorf("Undefined variable '%s'.", name.Lexeme)
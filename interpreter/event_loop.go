@@ -0,0 +1,292 @@
+package interpreter
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Clock is the event loop's source of time. Swapping in a ManualClock lets
+// tests run timers deterministically instead of sleeping.
+type Clock interface {
+	Now() time.Duration
+	Sleep(d time.Duration)
+}
+
+// RealClock measures wall-clock time since it was created.
+type RealClock struct {
+	start time.Time
+}
+
+func NewRealClock() *RealClock {
+	return &RealClock{start: time.Now()}
+}
+
+func (c *RealClock) Now() time.Duration {
+	return time.Since(c.start)
+}
+
+func (c *RealClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// ManualClock only moves when told to. Sleeping on it advances it instantly,
+// so draining the loop fires every timer in order without waiting.
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Duration
+}
+
+func NewManualClock() *ManualClock {
+	return &ManualClock{}
+}
+
+func (c *ManualClock) Now() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *ManualClock) Sleep(d time.Duration) {
+	c.Advance(d)
+}
+
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now += d
+}
+
+type timer struct {
+	id       float64
+	deadline time.Duration
+	interval time.Duration
+	seq      int
+	callback Callable
+}
+
+// timerQueue orders timers by deadline, breaking ties by scheduling order.
+type timerQueue []*timer
+
+func (q timerQueue) Len() int { return len(q) }
+func (q timerQueue) Less(a, b int) bool {
+	if q[a].deadline != q[b].deadline {
+		return q[a].deadline < q[b].deadline
+	}
+	return q[a].seq < q[b].seq
+}
+func (q timerQueue) Swap(a, b int)       { q[a], q[b] = q[b], q[a] }
+func (q *timerQueue) Push(x interface{}) { *q = append(*q, x.(*timer)) }
+func (q *timerQueue) Pop() interface{} {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
+
+// EventLoop runs timer callbacks and promise reactions. Promise reactions are
+// microtasks: they all run before the next timer fires.
+type EventLoop struct {
+	mu         sync.Mutex
+	clock      Clock
+	microtasks []func() error
+	timers     timerQueue
+	cancelled  map[float64]bool
+	nextID     float64
+	nextSeq    int
+	rejected   []*LoxPromise
+}
+
+func NewEventLoop(clock Clock) *EventLoop {
+	return &EventLoop{
+		clock:     clock,
+		cancelled: make(map[float64]bool),
+	}
+}
+
+func (l *EventLoop) enqueue(task func() error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.microtasks = append(l.microtasks, task)
+}
+
+func (l *EventLoop) schedule(callback Callable, delay, interval time.Duration) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.nextID++
+	l.nextSeq++
+	heap.Push(&l.timers, &timer{
+		id:       l.nextID,
+		deadline: l.clock.Now() + delay,
+		interval: interval,
+		seq:      l.nextSeq,
+		callback: callback,
+	})
+	return l.nextID
+}
+
+func (l *EventLoop) cancel(id float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cancelled[id] = true
+}
+
+func (l *EventLoop) nextMicrotask() func() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.microtasks) == 0 {
+		return nil
+	}
+	task := l.microtasks[0]
+	l.microtasks = l.microtasks[1:]
+	return task
+}
+
+// nextTimer pops the earliest live timer if it is due. Otherwise it reports
+// how long until one is, or that none are left.
+func (l *EventLoop) nextTimer() (due *timer, wait time.Duration, pending bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for len(l.timers) > 0 {
+		next := l.timers[0]
+		if l.cancelled[next.id] {
+			heap.Pop(&l.timers)
+			delete(l.cancelled, next.id)
+			continue
+		}
+		now := l.clock.Now()
+		if next.deadline > now {
+			return nil, next.deadline - now, true
+		}
+		heap.Pop(&l.timers)
+		if next.interval > 0 {
+			l.nextSeq++
+			heap.Push(&l.timers, &timer{
+				id:       next.id,
+				deadline: next.deadline + next.interval,
+				interval: next.interval,
+				seq:      l.nextSeq,
+				callback: next.callback,
+			})
+		}
+		return next, 0, true
+	}
+	return nil, 0, false
+}
+
+// run works through microtasks and timers until done reports true or there
+// is nothing left to do. When block is set it sleeps on the clock until the
+// next timer is due; otherwise it only runs what is due now.
+func (l *EventLoop) run(interpreter *Interpreter, done func() bool, block bool) error {
+	for !done() {
+		if task := l.nextMicrotask(); task != nil {
+			if err := dispatch(task); err != nil {
+				return err
+			}
+			continue
+		}
+
+		due, wait, pending := l.nextTimer()
+		if due != nil {
+			err := dispatch(func() error {
+				_, err := due.callback.Call(interpreter, nil)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !pending || !block {
+			break
+		}
+		l.clock.Sleep(wait)
+	}
+	return l.unhandledRejection()
+}
+
+// dispatch runs a callback from the loop. A Go panic in it, say in a native
+// function, becomes a runtime error rather than crashing the host program.
+func dispatch(callback func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &RuntimeError{Message: fmt.Sprintf("Callback panicked: %v.", r)}
+		}
+	}()
+	return callback()
+}
+
+// unhandledRejection returns the error behind a promise that was rejected by
+// a runtime error and never given a rejection handler.
+func (l *EventLoop) unhandledRejection() error {
+	l.mu.Lock()
+	rejected := l.rejected
+	l.rejected = nil
+	l.mu.Unlock()
+	for _, promise := range rejected {
+		if !promise.isHandled() && promise.cause != nil {
+			return promise.cause
+		}
+	}
+	return nil
+}
+
+func (l *EventLoop) trackRejection(promise *LoxPromise) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rejected = append(l.rejected, promise)
+}
+
+// TimerFunction implements setTimeout and setInterval.
+type TimerFunction struct {
+	repeat bool
+}
+
+func (f *TimerFunction) Arity() int {
+	return 2
+}
+
+func (f *TimerFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	callback, ok := arguments[0].(Callable)
+	if !ok || callback.Arity() != 0 {
+		return nil, fmt.Errorf("Timer callback must be a function with no parameters.")
+	}
+	ms, ok := arguments[1].(float64)
+	if !ok || ms < 0 {
+		return nil, fmt.Errorf("Timer delay must be a non-negative number.")
+	}
+	delay := time.Duration(ms * float64(time.Millisecond))
+
+	if f.repeat {
+		if delay <= 0 {
+			return nil, fmt.Errorf("Interval must be greater than zero.")
+		}
+		return interpreter.loop.schedule(callback, delay, delay), nil
+	}
+	return interpreter.loop.schedule(callback, delay, 0), nil
+}
+
+func (f *TimerFunction) String() string {
+	return "<native fn>"
+}
+
+// ClearTimerFunction implements clearTimeout and clearInterval.
+type ClearTimerFunction struct{}
+
+func (f *ClearTimerFunction) Arity() int {
+	return 1
+}
+
+func (f *ClearTimerFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	id, ok := arguments[0].(float64)
+	if !ok {
+		return nil, fmt.Errorf("Timer id must be a number.")
+	}
+	interpreter.loop.cancel(id)
+	return nil, nil
+}
+
+func (f *ClearTimerFunction) String() string {
+	return "<native fn>"
+}
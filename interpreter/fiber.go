@@ -0,0 +1,287 @@
+package interpreter
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/chase-compton/LOX_GO/scanner"
+)
+
+type fiberState int
+
+const (
+	fiberNew fiberState = iota
+	fiberRunning
+	fiberSuspended
+	fiberDone
+)
+
+// LoxFiber is a coroutine. Each fiber runs its function on its own goroutine
+// with its own forked Interpreter, so a fiber suspended deep inside nested
+// calls keeps its Go stack and environment chain while the resumer carries on
+// with its own. Control is handed back and forth over channels, so only one
+// fiber ever runs at a time.
+//
+// A suspended fiber's goroutine waits to be resumed for as long as the
+// interpreter lives, even once nothing can resume it. Interpreter.Close
+// cancels every suspended fiber, ending its goroutine.
+type LoxFiber struct {
+	function    Callable
+	interpreter *Interpreter
+	state       fiberState
+	// async marks the coroutine behind an async function call, which
+	// suspends on await rather than Fiber.yield.
+	async   bool
+	resumes chan fiberMessage
+	// caller receives the fiber's next yielded value or final result.
+	caller chan fiberResult
+	// cancelled is set when Close ends the fiber while it is suspended.
+	cancelled bool
+}
+
+type fiberMessage struct {
+	value  interface{}
+	caller chan fiberResult
+}
+
+type fiberResult struct {
+	value interface{}
+	err   error
+}
+
+func (f *LoxFiber) String() string {
+	return "<fiber>"
+}
+
+func (f *LoxFiber) Get(interpreter *Interpreter, name scanner.Token) (interface{}, error) {
+	switch name.Lexeme {
+	case "resume":
+		return &NativeMethod{Name: "resume", arity: VariadicArity, fn: func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+			value, err := optionalArgument(arguments)
+			if err != nil {
+				return nil, err
+			}
+			return f.resume(value)
+		}}, nil
+	case "transfer":
+		return &NativeMethod{Name: "transfer", arity: VariadicArity, fn: func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+			value, err := optionalArgument(arguments)
+			if err != nil {
+				return nil, err
+			}
+			return f.transfer(interpreter.fiber, value)
+		}}, nil
+	case "isDone":
+		return f.state == fiberDone, nil
+	}
+	return nil, undefinedProperty(name)
+}
+
+func (f *LoxFiber) checkResumable() error {
+	switch f.state {
+	case fiberDone:
+		return fmt.Errorf("Cannot resume a finished fiber.")
+	case fiberRunning:
+		return fmt.Errorf("Cannot resume a fiber that is already running.")
+	}
+	return nil
+}
+
+// resume runs the fiber until it yields or finishes and returns the value it
+// produced. An error raised inside the fiber is returned to the resumer.
+func (f *LoxFiber) resume(value interface{}) (interface{}, error) {
+	err := f.checkResumable()
+	if err != nil {
+		return nil, err
+	}
+	reply := make(chan fiberResult)
+	f.send(fiberMessage{value: value, caller: reply})
+	result := <-reply
+	return result.value, result.err
+}
+
+// transfer switches from the current fiber to f without making the current
+// fiber f's caller: whatever f yields goes to whoever last resumed current,
+// and current stays suspended until something resumes or transfers to it.
+func (f *LoxFiber) transfer(current *LoxFiber, value interface{}) (interface{}, error) {
+	if current == nil {
+		return f.resume(value)
+	}
+	err := f.checkResumable()
+	if err != nil {
+		return nil, err
+	}
+	current.state = fiberSuspended
+	f.send(fiberMessage{value: value, caller: current.caller})
+	return current.wait()
+}
+
+func (f *LoxFiber) send(message fiberMessage) {
+	starting := f.state == fiberNew
+	f.state = fiberRunning
+	if starting {
+		f.interpreter.fibers.add(f)
+		go f.run(message)
+	} else {
+		f.resumes <- message
+	}
+}
+
+func (f *LoxFiber) run(message fiberMessage) {
+	f.caller = message.caller
+	var value interface{}
+	var err error
+	defer func() {
+		// A Go panic, say in a native function, fails the fiber rather than
+		// the whole program.
+		if r := recover(); r != nil {
+			value, err = nil, f.panicked(r)
+		}
+		f.interpreter.fibers.remove(f)
+		if f.cancelled {
+			// Nothing is waiting for the result.
+			return
+		}
+		f.state = fiberDone
+		f.caller <- fiberResult{value: value, err: err}
+	}()
+
+	var arguments []interface{}
+	if f.function.Arity() == 1 {
+		arguments = []interface{}{message.value}
+	}
+	value, err = f.function.Call(f.interpreter, arguments)
+}
+
+// panicked returns the error a fiber fails with when its goroutine panics.
+// The resumer reports it at the call to resume, except for an async call,
+// whose promise it rejects.
+func (f *LoxFiber) panicked(r interface{}) error {
+	if f.async {
+		return &RuntimeError{Message: fmt.Sprintf("Async function panicked: %v.", r)}
+	}
+	return fmt.Errorf("Fiber panicked: %v.", r)
+}
+
+// yield suspends the fiber, handing value to its caller, and returns the
+// value passed to the next resume or transfer.
+func (f *LoxFiber) yield(value interface{}) (interface{}, error) {
+	f.state = fiberSuspended
+	f.caller <- fiberResult{value: value}
+	return f.wait()
+}
+
+func (f *LoxFiber) wait() (interface{}, error) {
+	message, ok := <-f.resumes
+	if !ok {
+		return nil, fmt.Errorf("Fiber was cancelled.")
+	}
+	f.caller = message.caller
+	return message.value, nil
+}
+
+// cancel ends a suspended fiber: the yield it is waiting in fails, unwinding
+// its calls, and its result is dropped.
+func (f *LoxFiber) cancel() {
+	f.cancelled = true
+	f.state = fiberDone
+	close(f.resumes)
+}
+
+// fiberSet holds the fibers of an interpreter and its forks that have
+// started and not yet finished.
+type fiberSet struct {
+	mu     sync.Mutex
+	fibers map[*LoxFiber]bool
+}
+
+func newFiberSet() *fiberSet {
+	return &fiberSet{fibers: make(map[*LoxFiber]bool)}
+}
+
+func (s *fiberSet) add(f *LoxFiber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fibers[f] = true
+}
+
+func (s *fiberSet) remove(f *LoxFiber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.fibers, f)
+}
+
+// cancelSuspended cancels every fiber waiting to be resumed.
+func (s *fiberSet) cancelSuspended() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for f := range s.fibers {
+		if f.state == fiberSuspended {
+			f.cancel()
+			delete(s.fibers, f)
+		}
+	}
+}
+
+func optionalArgument(arguments []interface{}) (interface{}, error) {
+	if len(arguments) > 1 {
+		return nil, fmt.Errorf("Expected at most 1 argument but got %d.", len(arguments))
+	}
+	if len(arguments) == 1 {
+		return arguments[0], nil
+	}
+	return nil, nil
+}
+
+// FiberClass is behind the built-in Fiber type: calling `Fiber` creates a
+// fiber, and `Fiber.yield(value)` suspends the fiber that is currently
+// running.
+type FiberClass struct{}
+
+func (c *FiberClass) Arity() int {
+	return 1
+}
+
+func (c *FiberClass) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	function, ok := arguments[0].(Callable)
+	if !ok {
+		return nil, fmt.Errorf("Fiber body must be a function.")
+	}
+	if arity := function.Arity(); arity != 0 && arity != 1 {
+		return nil, fmt.Errorf("Fiber function must take at most one parameter.")
+	}
+
+	return newFiber(function, interpreter), nil
+}
+
+func newFiber(function Callable, interpreter *Interpreter) *LoxFiber {
+	fiber := &LoxFiber{
+		function: function,
+		resumes:  make(chan fiberMessage),
+	}
+	fiber.interpreter = interpreter.fork(fiber)
+	return fiber
+}
+
+func (c *FiberClass) Get(interpreter *Interpreter, name scanner.Token) (interface{}, error) {
+	if name.Lexeme == "yield" {
+		return &NativeMethod{Name: "yield", arity: VariadicArity, fn: func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+			value, err := optionalArgument(arguments)
+			if err != nil {
+				return nil, err
+			}
+			if interpreter.fiber == nil {
+				return nil, fmt.Errorf("Cannot yield from outside a fiber.")
+			}
+			if interpreter.fiber.async {
+				return nil, fmt.Errorf("Cannot yield from an async function.")
+			}
+			return interpreter.fiber.yield(value)
+		}}, nil
+	}
+	return nil, undefinedProperty(name)
+}
+
+func (c *FiberClass) String() string {
+	return "<class Fiber>"
+}
@@ -5,52 +5,281 @@ import (
 	"github.com/chase-compton/LOX_GO/ast"
 	"github.com/chase-compton/LOX_GO/errors"
 	"github.com/chase-compton/LOX_GO/scanner"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"sync"
 )
 
+// DefaultMaxCallDepth is how deeply Lox calls may nest before a call fails
+// with "Stack overflow.". It stays well inside the Go stack limit.
+const DefaultMaxCallDepth = 10000
+
 type Interpreter struct {
+	diagnostics errors.Diagnostics
 	environment *Environment
 	globals     *Environment
-	locals      map[ast.Expr]int
+	locals      *localsTable
+	// fiber is the fiber this interpreter runs, or nil for the main fiber.
+	fiber *LoxFiber
+	// fibers are the fibers started by this interpreter and its forks that
+	// haven't finished.
+	fibers *fiberSet
+	loop   *EventLoop
+	// callDepth counts the calls in progress on this interpreter's
+	// goroutine; fibers and tasks run on their own and start from zero.
+	callDepth    int
+	maxCallDepth int
+	// contracts is whether assert statements and requires and ensures
+	// clauses are checked.
+	contracts bool
+	// output is where print statements write. Forks share it, so prints
+	// from different tasks don't interleave.
+	output *sharedOutput
+	// frames is the stack of Lox function calls in progress. callLine holds
+	// the line of a call on its way into a function, which takes it as the
+	// line of its frame.
+	frames   []CallFrame
+	callLine int
 }
 
-func NewInterpreter() *Interpreter {
+// NewInterpreter returns an interpreter that reports runtime errors to
+// diagnostics.
+func NewInterpreter(diagnostics errors.Diagnostics) *Interpreter {
 	globals := NewEnvironment(nil)
 	interpreter := &Interpreter{
-		globals:     globals,
-		environment: globals,
-		locals:      make(map[ast.Expr]int),
+		diagnostics:  diagnostics,
+		globals:      globals,
+		environment:  globals,
+		locals:       &localsTable{depths: make(map[ast.Expr]int)},
+		fibers:       newFiberSet(),
+		loop:         NewEventLoop(NewRealClock()),
+		maxCallDepth: DefaultMaxCallDepth,
+		contracts:    true,
+		output:       &sharedOutput{writer: os.Stdout},
 	}
 
 	// Define native functions
 	interpreter.globals.Define("clock", &ClockFunction{})
+	interpreter.globals.Define("len", &LenFunction{})
+	interpreter.globals.Define("set", &SetFromFunction{})
+	interpreter.globals.Define("setTimeout", &TimerFunction{})
+	interpreter.globals.Define("setInterval", &TimerFunction{repeat: true})
+	interpreter.globals.Define("clearTimeout", &ClearTimerFunction{})
+	interpreter.globals.Define("clearInterval", &ClearTimerFunction{})
+	interpreter.globals.Define("type", &TypeFunction{})
+	interpreter.globals.Define("isInstance", &IsInstanceFunction{})
+	interpreter.globals.Define("fields", &FieldsFunction{})
+	interpreter.globals.Define("methods", &MethodsFunction{})
+	interpreter.globals.Define("superclassOf", &SuperclassOfFunction{})
+	interpreter.globals.Define("hasattr", &HasAttrFunction{})
+	interpreter.globals.Define("getattr", &GetAttrFunction{})
+	interpreter.globals.Define("setattr", &SetAttrFunction{})
+	interpreter.globals.Define("delattr", &DelAttrFunction{})
+	for _, class := range builtinTypes {
+		interpreter.globals.Define(class.Name, class)
+	}
 
 	return interpreter
 }
 
+// fork returns an interpreter for running a fiber or task. It shares the globals and
+// resolved locals with i but tracks its own current environment, starting at
+// the globals since the fiber's function carries its own closure.
+func (i *Interpreter) fork(fiber *LoxFiber) *Interpreter {
+	return &Interpreter{
+		diagnostics:  i.diagnostics,
+		globals:      i.globals,
+		environment:  i.globals,
+		locals:       i.locals,
+		fiber:        fiber,
+		fibers:       i.fibers,
+		loop:         i.loop,
+		maxCallDepth: i.maxCallDepth,
+		contracts:    i.contracts,
+		output:       i.output,
+	}
+}
+
+// Close cancels the fibers left suspended, ending their goroutines, which
+// would otherwise wait to be resumed for as long as the program runs.
+// Resuming a cancelled fiber fails as if it had finished.
+func (i *Interpreter) Close() {
+	i.fibers.cancelSuspended()
+}
+
+// SetMaxCallDepth sets how deeply calls may nest. A depth of zero or less
+// removes the limit, leaving deep recursion to crash the Go runtime.
+func (i *Interpreter) SetMaxCallDepth(depth int) {
+	i.maxCallDepth = depth
+}
+
+// SetOutput sends the output of print statements to w instead of standard
+// output.
+func (i *Interpreter) SetOutput(w io.Writer) {
+	i.output.mu.Lock()
+	defer i.output.mu.Unlock()
+	i.output.writer = w
+}
+
+// sharedOutput is where print statements write, shared by tasks.
+type sharedOutput struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func (o *sharedOutput) println(text string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	fmt.Fprintln(o.writer, text)
+}
+
+// SetClock replaces the clock that drives timers, typically with a
+// ManualClock so tests can control time.
+func (i *Interpreter) SetClock(clock Clock) {
+	i.loop.clock = clock
+}
+
+// RunEventLoop runs pending timers and promise callbacks until none remain,
+// sleeping on the clock between timers. Errors are reported like those from
+// Interpret.
+func (i *Interpreter) RunEventLoop() error {
+	return i.reportRuntimeError(i.loop.run(i, func() bool { return false }, true))
+}
+
+// RunDueEvents runs only the callbacks that are due at the clock's current
+// time, without waiting for later timers.
+func (i *Interpreter) RunDueEvents() error {
+	return i.reportRuntimeError(i.loop.run(i, func() bool { return false }, false))
+}
+
+// reportRuntimeError reports an error that stopped the program. Anything
+// other than a RuntimeError is a fault in the interpreter.
+func (i *Interpreter) reportRuntimeError(err error) error {
+	if runtimeErr, ok := err.(*RuntimeError); ok {
+		i.diagnostics.Report(runtimeErr.Diagnostic())
+	} else if err != nil {
+		i.diagnostics.Report(errors.InternalError(err.Error()))
+	}
+	return err
+}
+
+// Frames returns a copy of the current call stack, outermost first.
+func (i *Interpreter) Frames() []CallFrame {
+	return append([]CallFrame(nil), i.frames...)
+}
+
+func (i *Interpreter) pushFrame(frame CallFrame) {
+	i.frames = append(i.frames, frame)
+}
+
+func (i *Interpreter) popFrame() {
+	i.frames = i.frames[:len(i.frames)-1]
+}
+
+// takeCallLine returns the line of the call being made and clears it, so a
+// later call from native code doesn't reuse it.
+func (i *Interpreter) takeCallLine() int {
+	line := i.callLine
+	i.callLine = 0
+	return line
+}
+
+// recordFrames attaches the current call stack to a runtime error the first
+// time it passes through a function, which is where the stack is deepest.
+func (i *Interpreter) recordFrames(err error) {
+	if runtimeErr, ok := err.(*RuntimeError); ok && runtimeErr.Frames == nil {
+		runtimeErr.Frames = i.Frames()
+	}
+}
+
 var _ ast.ExprVisitor = &Interpreter{}
 var _ ast.StmtVisitor = &Interpreter{}
 
-func (i *Interpreter) Interpret(statements []ast.Stmt) error {
+func (i *Interpreter) Interpret(statements []ast.Stmt) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			// Recover from panic if any
+			// A panic is a bug in the interpreter, but report it rather than
+			// exiting silently or crashing the REPL.
+			err = i.reportRuntimeError(fmt.Errorf("%v", r))
 		}
 	}()
 
 	for _, statement := range statements {
 		_, err := i.execute(statement)
 		if err != nil {
-			if runtimeErr, ok := err.(*RuntimeError); ok {
-				errors.ReportRuntimeError(runtimeErr.Token.Line, runtimeErr.Message)
-				return err
-			}
+			return i.reportRuntimeError(err)
 		}
 	}
 	return nil
 }
 
+// Evaluate evaluates a resolved expression in the global scope, reporting
+// any runtime error as Interpret does.
+func (i *Interpreter) Evaluate(expr ast.Expr) (value interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = i.reportRuntimeError(fmt.Errorf("%v", r))
+		}
+	}()
+
+	value, err = i.evaluate(expr)
+	if err != nil {
+		return nil, i.reportRuntimeError(err)
+	}
+	return value, nil
+}
+
+// CallValue calls callee with arguments from outside any Lox code, checking
+// that it can be called with them and reporting any runtime error.
+func (i *Interpreter) CallValue(callee interface{}, arguments []interface{}) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = i.reportRuntimeError(fmt.Errorf("%v", r))
+		}
+	}()
+
+	function, ok := callee.(Callable)
+	if !ok {
+		return nil, i.reportRuntimeError(&RuntimeError{
+			Message: "Can only call functions and classes.",
+			Code:    errors.CodeNotCallable,
+		})
+	}
+	if arity := function.Arity(); arity != VariadicArity && len(arguments) != arity {
+		return nil, i.reportRuntimeError(&RuntimeError{
+			Message: fmt.Sprintf("Expected %d arguments but got %d.", arity, len(arguments)),
+			Code:    errors.CodeArityMismatch,
+		})
+	}
+
+	result, err = i.call(scanner.Token{}, function, arguments)
+	if err != nil {
+		return nil, i.reportRuntimeError(err)
+	}
+	return result, nil
+}
+
+// DefineGlobal defines, or redefines, a global variable.
+func (i *Interpreter) DefineGlobal(name string, value interface{}) {
+	i.globals.Define(name, value)
+}
+
+// LookupGlobal returns the value of a global variable and whether it is
+// defined.
+func (i *Interpreter) LookupGlobal(name string) (interface{}, bool) {
+	return i.globals.lookup(name)
+}
+
 func (i *Interpreter) Resolve(expr ast.Expr, depth int) {
-	i.locals[expr] = depth
+	i.locals.set(expr, depth)
+}
+
+// GlobalNames lists the names defined in the global scope, natives
+// included.
+func (i *Interpreter) GlobalNames() []string {
+	return i.globals.names()
 }
 
 func (i *Interpreter) VisitExpressionStmt(stmt *ast.ExpressionStmt) (interface{}, error) {
@@ -63,7 +292,7 @@ func (i *Interpreter) VisitPrintStmt(stmt *ast.PrintStmt) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	fmt.Println(stringify(value))
+	i.output.println(stringify(value))
 	return nil, nil
 }
 
@@ -77,7 +306,7 @@ func (i *Interpreter) VisitUnaryExpr(expr *ast.Unary) (interface{}, error) {
 	case scanner.MINUS:
 		number, ok := right.(float64)
 		if !ok {
-			return nil, i.newRuntimeError(expr.Operator, "Operand must be a number.")
+			return nil, i.newTypeError(expr.Operator, "Operand must be a number.")
 		}
 		return -number, nil
 	case scanner.BANG:
@@ -104,7 +333,7 @@ func (i *Interpreter) VisitBinaryExpr(expr *ast.Binary) (interface{}, error) {
 		leftNum, ok1 := left.(float64)
 		rightNum, ok2 := right.(float64)
 		if !ok1 || !ok2 {
-			return nil, i.newRuntimeError(expr.Operator, "Operands must be numbers.")
+			return nil, i.newTypeError(expr.Operator, "Operands must be numbers.")
 		}
 		return leftNum - rightNum, nil
 
@@ -112,7 +341,7 @@ func (i *Interpreter) VisitBinaryExpr(expr *ast.Binary) (interface{}, error) {
 		leftNum, ok1 := left.(float64)
 		rightNum, ok2 := right.(float64)
 		if !ok1 || !ok2 {
-			return nil, i.newRuntimeError(expr.Operator, "Operands must be numbers.")
+			return nil, i.newTypeError(expr.Operator, "Operands must be numbers.")
 		}
 		if rightNum == 0 {
 			return nil, i.newRuntimeError(expr.Operator, "Division by zero.")
@@ -122,7 +351,7 @@ func (i *Interpreter) VisitBinaryExpr(expr *ast.Binary) (interface{}, error) {
 		l, ok1 := left.(float64)
 		r, ok2 := right.(float64)
 		if !ok1 || !ok2 {
-			return nil, i.newRuntimeError(expr.Operator, "Operands must be numbers.")
+			return nil, i.newTypeError(expr.Operator, "Operands must be numbers.")
 		}
 		return l * r, nil
 	case scanner.PLUS:
@@ -132,43 +361,43 @@ func (i *Interpreter) VisitBinaryExpr(expr *ast.Binary) (interface{}, error) {
 				return left.(float64) + r, nil
 			}
 			// Left is number, right is not
-			return nil, i.newRuntimeError(expr.Operator, "Operands must be two numbers or two strings.")
+			return nil, i.newTypeError(expr.Operator, "Operands must be two numbers or two strings.")
 		case string:
 			if r, ok := right.(string); ok {
 				return left.(string) + r, nil
 			}
 			// Left is string, right is not
-			return nil, i.newRuntimeError(expr.Operator, "Operands must be two numbers or two strings.")
+			return nil, i.newTypeError(expr.Operator, "Operands must be two numbers or two strings.")
 		default:
 			// Left is neither number nor string
-			return nil, i.newRuntimeError(expr.Operator, "Operands must be two numbers or two strings.")
+			return nil, i.newTypeError(expr.Operator, "Operands must be two numbers or two strings.")
 		}
 	case scanner.GREATER:
 		leftNum, ok1 := left.(float64)
 		rightNum, ok2 := right.(float64)
 		if !ok1 || !ok2 {
-			return nil, i.newRuntimeError(expr.Operator, "Operands must be numbers.")
+			return nil, i.newTypeError(expr.Operator, "Operands must be numbers.")
 		}
 		return leftNum > rightNum, nil
 	case scanner.GREATER_EQUAL:
 		leftNum, ok1 := left.(float64)
 		rightNum, ok2 := right.(float64)
 		if !ok1 || !ok2 {
-			return nil, i.newRuntimeError(expr.Operator, "Operands must be numbers.")
+			return nil, i.newTypeError(expr.Operator, "Operands must be numbers.")
 		}
 		return leftNum >= rightNum, nil
 	case scanner.LESS:
 		leftNum, ok1 := left.(float64)
 		rightNum, ok2 := right.(float64)
 		if !ok1 || !ok2 {
-			return nil, i.newRuntimeError(expr.Operator, "Operands must be numbers.")
+			return nil, i.newTypeError(expr.Operator, "Operands must be numbers.")
 		}
 		return leftNum < rightNum, nil
 	case scanner.LESS_EQUAL:
 		leftNum, ok1 := left.(float64)
 		rightNum, ok2 := right.(float64)
 		if !ok1 || !ok2 {
-			return nil, i.newRuntimeError(expr.Operator, "Operands must be numbers.")
+			return nil, i.newTypeError(expr.Operator, "Operands must be numbers.")
 		}
 		return leftNum <= rightNum, nil
 	case scanner.BANG_EQUAL:
@@ -193,7 +422,7 @@ func (i *Interpreter) VisitVariableExpr(expr *ast.Variable) (interface{}, error)
 }
 
 func (i *Interpreter) lookUpVariable(name scanner.Token, expr ast.Expr) (interface{}, error) {
-	if distance, ok := i.locals[expr]; ok {
+	if distance, ok := i.locals.get(expr); ok {
 		return i.environment.GetAt(distance, name.Lexeme)
 	} else {
 		return i.globals.Get(name)
@@ -220,20 +449,187 @@ func (i *Interpreter) VisitAssignExpr(expr *ast.Assign) (interface{}, error) {
 		return nil, err
 	}
 
-	distance, ok := i.locals[expr]
+	err = i.assignVariable(expr, expr.Name, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func (i *Interpreter) assignVariable(expr ast.Expr, name scanner.Token, value interface{}) error {
+	distance, ok := i.locals.get(expr)
 	if ok {
-		err = i.environment.AssignAt(distance, expr.Name, value)
-	} else {
-		err = i.globals.Assign(expr.Name, value)
+		return i.environment.AssignAt(distance, name, value)
+	}
+	return i.globals.Assign(name, value)
+}
+
+func (i *Interpreter) VisitListExpr(expr *ast.List) (interface{}, error) {
+	elements := make([]interface{}, 0, len(expr.Elements))
+	for _, element := range expr.Elements {
+		value, err := i.evaluate(element)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, value)
+	}
+	return NewLoxList(elements), nil
+}
+
+func (i *Interpreter) VisitTupleExpr(expr *ast.Tuple) (interface{}, error) {
+	elements := make([]interface{}, 0, len(expr.Elements))
+	for _, element := range expr.Elements {
+		value, err := i.evaluate(element)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, value)
+	}
+	return NewLoxTuple(elements), nil
+}
+
+func (i *Interpreter) VisitIndexExpr(expr *ast.Index) (interface{}, error) {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	index, err := i.evaluate(expr.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	var elements []interface{}
+	switch sequence := object.(type) {
+	case *LoxList:
+		elements = sequence.Elements
+	case *LoxTuple:
+		elements = sequence.Elements
+	default:
+		return nil, i.newRuntimeError(expr.Bracket, "Can only index lists and tuples.")
+	}
+
+	n, err := i.elementIndex(expr.Bracket, index, len(elements))
+	if err != nil {
+		return nil, err
 	}
+	return elements[n], nil
+}
 
+func (i *Interpreter) VisitIndexSetExpr(expr *ast.IndexSet) (interface{}, error) {
+	object, err := i.evaluate(expr.Object)
 	if err != nil {
 		return nil, err
 	}
+	index, err := i.evaluate(expr.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	switch object.(type) {
+	case *LoxList:
+	case *LoxTuple:
+		return nil, i.newRuntimeError(expr.Bracket, "Tuples are immutable.")
+	default:
+		return nil, i.newRuntimeError(expr.Bracket, "Can only assign to list elements.")
+	}
+	list := object.(*LoxList)
 
+	n, err := i.elementIndex(expr.Bracket, index, len(list.Elements))
+	if err != nil {
+		return nil, err
+	}
+	value, err := i.evaluate(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+	list.Elements[n] = value
 	return value, nil
 }
 
+func (i *Interpreter) elementIndex(bracket scanner.Token, index interface{}, length int) (int, error) {
+	number, ok := index.(float64)
+	if !ok || number != math.Trunc(number) {
+		return 0, i.newRuntimeError(bracket, "Index must be an integer.")
+	}
+	if number < 0 || number >= float64(length) {
+		return 0, i.newRuntimeError(bracket, "Index out of range.")
+	}
+	return int(number), nil
+}
+
+func (i *Interpreter) VisitDestructureAssignExpr(expr *ast.DestructureAssign) (interface{}, error) {
+	value, err := i.evaluate(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	values, _, err := i.unpack(expr.Bracket, value, len(expr.Targets), false)
+	if err != nil {
+		return nil, err
+	}
+	for n, target := range expr.Targets {
+		err := i.assignVariable(target, target.Name, values[n])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+func (i *Interpreter) VisitDestructureVarStmt(stmt *ast.DestructureVarStmt) (interface{}, error) {
+	value, err := i.evaluate(stmt.Initializer)
+	if err != nil {
+		return nil, err
+	}
+
+	if stmt.Open.Type == scanner.LEFT_BRACE {
+		instance, ok := value.(*LoxInstance)
+		if !ok {
+			return nil, i.newRuntimeError(stmt.Open, "Can only destructure fields of an instance.")
+		}
+		for _, name := range stmt.Names {
+			field, err := instance.Get(i, name)
+			if err != nil {
+				return nil, err
+			}
+			i.environment.Define(name.Lexeme, field)
+		}
+		return nil, nil
+	}
+
+	values, rest, err := i.unpack(stmt.Open, value, len(stmt.Names), stmt.Rest != nil)
+	if err != nil {
+		return nil, err
+	}
+	for n, name := range stmt.Names {
+		i.environment.Define(name.Lexeme, values[n])
+	}
+	if stmt.Rest != nil {
+		i.environment.Define(stmt.Rest.Lexeme, NewLoxList(append([]interface{}{}, rest...)))
+	}
+	return nil, nil
+}
+
+// unpack splits a list or tuple into its first count elements and the remainder. The
+// remainder must be empty unless the pattern has a rest variable.
+func (i *Interpreter) unpack(token scanner.Token, value interface{}, count int, hasRest bool) ([]interface{}, []interface{}, error) {
+	var elements []interface{}
+	switch sequence := value.(type) {
+	case *LoxList:
+		elements = sequence.Elements
+	case *LoxTuple:
+		elements = sequence.Elements
+	default:
+		return nil, nil, i.newRuntimeError(token, "Can only destructure a list or tuple.")
+	}
+	if len(elements) < count || (!hasRest && len(elements) > count) {
+		return nil, nil, i.newRuntimeError(token,
+			fmt.Sprintf("Expected %d values to unpack but got %d.", count, len(elements)))
+	}
+	return elements[:count], elements[count:], nil
+}
+
 func (i *Interpreter) VisitBlockStmt(stmt *ast.BlockStmt) (interface{}, error) {
 	return i.executeBlock(stmt.Statements, NewEnvironment(i.environment))
 }
@@ -290,13 +686,74 @@ func (i *Interpreter) VisitWhileStmt(stmt *ast.WhileStmt) (interface{}, error) {
 	return nil, nil
 }
 
+func (i *Interpreter) VisitForInStmt(stmt *ast.ForInStmt) (interface{}, error) {
+	iterable, err := i.evaluate(stmt.Iterable)
+	if err != nil {
+		return nil, err
+	}
+
+	elements, ok := iterate(iterable)
+	if !ok {
+		return nil, i.newRuntimeError(stmt.In, "Can only iterate over lists, tuples, sets and strings.")
+	}
+	for _, element := range elements {
+		env := NewEnvironment(i.environment)
+		env.Define(stmt.Name.Lexeme, element)
+		_, err := i.executeBlock([]ast.Stmt{stmt.Body}, env)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// iterate returns a snapshot of the elements of an iterable value, so the
+// loop body is free to modify the collection it is walking.
+func iterate(value interface{}) ([]interface{}, bool) {
+	switch v := value.(type) {
+	case *LoxList:
+		return append([]interface{}{}, v.Elements...), true
+	case *LoxTuple:
+		return v.Elements, true
+	case *LoxSet:
+		return v.Elements(), true
+	case string:
+		var elements []interface{}
+		for _, r := range v {
+			elements = append(elements, string(r))
+		}
+		return elements, true
+	}
+	return nil, false
+}
+
 func (i *Interpreter) VisitFunctionStmt(stmt *ast.FunctionStmt) (interface{}, error) {
+	decorators, err := i.evaluateDecorators(stmt.Decorators)
+	if err != nil {
+		return nil, err
+	}
+
 	function := NewLoxFunction(stmt, i.environment, false)
+	// Define the undecorated function first so the name exists while the
+	// decorators run, then rebind it to whatever they return.
 	i.environment.Define(stmt.Name.Lexeme, function)
+	if len(decorators) == 0 {
+		return nil, nil
+	}
+
+	value, err := i.decorate(decorators, function)
+	if err != nil {
+		return nil, err
+	}
+	i.environment.Define(stmt.Name.Lexeme, value)
 	return nil, nil
 }
 
 func (i *Interpreter) VisitReturnStmt(stmt *ast.ReturnStmt) (interface{}, error) {
+	if stmt.TailCall {
+		return i.tailCall(stmt.Value.(*ast.Call))
+	}
+
 	var value interface{}
 	var err error
 	if stmt.Value != nil {
@@ -310,36 +767,227 @@ func (i *Interpreter) VisitReturnStmt(stmt *ast.ReturnStmt) (interface{}, error)
 }
 
 func (i *Interpreter) VisitCallExpr(expr *ast.Call) (interface{}, error) {
-	callee, err := i.evaluate(expr.Callee)
+	function, arguments, err := i.prepareCall(expr)
 	if err != nil {
 		return nil, err
 	}
 
+	if i.maxCallDepth > 0 && i.callDepth >= i.maxCallDepth {
+		return nil, &RuntimeError{
+			Token:   expr.Paren,
+			Message: "Stack overflow.",
+			Code:    errors.CodeStackOverflow,
+		}
+	}
+	i.callDepth++
+	defer func() { i.callDepth-- }()
+
+	return i.call(expr.Paren, function, arguments)
+}
+
+// prepareCall evaluates the callee and arguments of a call and checks that
+// the callee can be called with them.
+func (i *Interpreter) prepareCall(expr *ast.Call) (Callable, []interface{}, error) {
+	callee, err := i.evaluateCallee(expr.Callee)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var arguments []interface{}
 	for _, argument := range expr.Arguments {
 		argValue, err := i.evaluate(argument)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		arguments = append(arguments, argValue)
 	}
 
 	function, ok := callee.(Callable)
 	if !ok {
-		return nil, &RuntimeError{
+		return nil, nil, &RuntimeError{
 			Token:   expr.Paren,
 			Message: "Can only call functions and classes.",
+			Code:    errors.CodeNotCallable,
 		}
 	}
 
-	if len(arguments) != function.Arity() {
-		return nil, &RuntimeError{
+	if arity := function.Arity(); arity != VariadicArity && len(arguments) != arity {
+		return nil, nil, &RuntimeError{
 			Token:   expr.Paren,
 			Message: fmt.Sprintf("Expected %d arguments but got %d.", function.Arity(), len(arguments)),
+			Code:    errors.CodeArityMismatch,
 		}
 	}
+	return function, arguments, nil
+}
+
+func (i *Interpreter) call(paren scanner.Token, function Callable, arguments []interface{}) (interface{}, error) {
+	i.callLine = paren.Line
+	result, err := function.Call(i, arguments)
+	i.callLine = 0
+	if err != nil {
+		// Natives report plain errors; attribute them to the call site.
+		if _, ok := err.(*RuntimeError); !ok {
+			return nil, i.newRuntimeError(paren, err.Error())
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// tailCall evaluates the callee and arguments of a returned call. Calls to
+// plain Lox functions are handed back to the enclosing LoxFunction.call as a
+// TailCall so deep tail recursion runs in constant Go stack; anything else,
+// such as natives, classes and async functions, is called here as usual.
+func (i *Interpreter) tailCall(expr *ast.Call) (interface{}, error) {
+	function, arguments, err := i.prepareCall(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	if target, ok := function.(*LoxFunction); ok && !target.Declaration.IsAsync && !target.IsInitializer {
+		return nil, &Return{TailCall: &TailCall{Function: target, Arguments: arguments}}
+	}
 
-	return function.Call(i, arguments)
+	value, err := i.call(expr.Paren, function, arguments)
+	if err != nil {
+		return nil, err
+	}
+	return nil, &Return{Value: value}
+}
+
+func (i *Interpreter) VisitSpawnExpr(expr *ast.Spawn) (interface{}, error) {
+	function, arguments, err := i.prepareCall(expr.Call)
+	if err != nil {
+		return nil, err
+	}
+
+	task := &LoxTask{done: make(chan struct{})}
+	worker := i.fork(nil)
+	go func() {
+		defer close(task.done)
+		defer func() {
+			// A Go panic, say in a native function, fails the task rather
+			// than the whole program.
+			if r := recover(); r != nil {
+				task.value = nil
+				task.err = worker.newRuntimeError(expr.Call.Paren, fmt.Sprintf("Task panicked: %v.", r))
+			}
+		}()
+		task.value, task.err = worker.call(expr.Call.Paren, function, arguments)
+	}()
+	return task, nil
+}
+
+func (i *Interpreter) VisitAwaitExpr(expr *ast.Await) (interface{}, error) {
+	value, err := i.evaluate(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+	promise, ok := value.(*LoxPromise)
+	if !ok {
+		promise = newPromise(i.loop)
+		promise.resolve(value)
+	}
+
+	if i.fiber != nil && i.fiber.async {
+		// Suspend the async call; its driver resumes it once promise settles.
+		_, err := i.fiber.yield(promise)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Top-level code has no caller to return to, so it runs the event loop
+		// until the promise settles.
+		err := i.loop.run(i, promise.isSettled, true)
+		if err != nil {
+			return nil, err
+		}
+		if !promise.isSettled() {
+			return nil, i.newRuntimeError(expr.Keyword, "Awaited promise can never settle.")
+		}
+	}
+
+	if promise.state == promiseRejected {
+		return nil, promise.rejectionError(expr.Keyword)
+	}
+	return promise.value, nil
+}
+
+func (i *Interpreter) VisitSelectStmt(stmt *ast.SelectStmt) (interface{}, error) {
+	// As in Go, every channel and sent value is evaluated before choosing.
+	cases := make([]reflect.SelectCase, 0, len(stmt.Cases)+1)
+	channels := make([]*LoxChannel, 0, len(stmt.Cases))
+	for _, selectCase := range stmt.Cases {
+		value, err := i.evaluate(selectCase.Channel)
+		if err != nil {
+			return nil, err
+		}
+		channel, ok := value.(*LoxChannel)
+		if !ok {
+			return nil, i.newRuntimeError(selectCase.Keyword, "Can only select on channels.")
+		}
+		channels = append(channels, channel)
+
+		if selectCase.Value == nil {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(channel.ch)})
+			continue
+		}
+		sent, err := i.evaluate(selectCase.Value)
+		if err != nil {
+			return nil, err
+		}
+		if channel.isClosed() {
+			return nil, i.newRuntimeError(selectCase.Keyword, "Cannot send on a closed channel.")
+		}
+		cases = append(cases, reflect.SelectCase{
+			Dir:  reflect.SelectSend,
+			Chan: reflect.ValueOf(channel.ch),
+			Send: reflect.ValueOf(&sent).Elem(),
+		})
+	}
+	if stmt.Default != nil {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	}
+
+	chosen, received, err := i.selectCase(stmt, cases, channels)
+	if err != nil {
+		return nil, err
+	}
+	if chosen == len(stmt.Cases) {
+		return i.execute(stmt.Default)
+	}
+
+	selectCase := stmt.Cases[chosen]
+	env := NewEnvironment(i.environment)
+	if selectCase.Name != nil {
+		var value interface{}
+		if received.IsValid() && !received.IsNil() {
+			value = received.Interface()
+		}
+		env.Define(selectCase.Name.Lexeme, value)
+	}
+	return i.executeBlock([]ast.Stmt{selectCase.Body}, env)
+}
+
+// selectCase waits for one of cases to proceed. Another task may close a
+// channel while a send to it waits, which fails the select at that send.
+func (i *Interpreter) selectCase(stmt *ast.SelectStmt, cases []reflect.SelectCase, channels []*LoxChannel) (chosen int, received reflect.Value, err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		for n, selectCase := range stmt.Cases {
+			if selectCase.Value != nil && channels[n].isClosed() {
+				err = i.newRuntimeError(selectCase.Keyword, "Cannot send on a closed channel.")
+				return
+			}
+		}
+		panic(r)
+	}()
+	chosen, received, _ = reflect.Select(cases)
+	return chosen, received, nil
 }
 
 func (i *Interpreter) VisitClassStmt(stmt *ast.ClassStmt) (interface{}, error) {
@@ -357,54 +1005,124 @@ func (i *Interpreter) VisitClassStmt(stmt *ast.ClassStmt) (interface{}, error) {
 				Message: "Superclass must be a class.",
 			}
 		}
+		if superclass.builtin {
+			return nil, &RuntimeError{
+				Token:   stmt.Superclass.Name,
+				Message: fmt.Sprintf("Can't inherit from built-in type '%s'.", superclass.Name),
+			}
+		}
 	}
 
 	i.environment.Define(stmt.Name.Lexeme, nil)
 
+	decorators, err := i.evaluateDecorators(stmt.Decorators)
+	if err != nil {
+		return nil, err
+	}
+	methodDecorators := make(map[string][]decorator)
+	for _, method := range stmt.Methods {
+		evaluated, err := i.evaluateDecorators(method.Decorators)
+		if err != nil {
+			return nil, err
+		}
+		methodDecorators[method.Name.Lexeme] = evaluated
+	}
+
 	if stmt.Superclass != nil {
 		// Begin a new scope for 'super'
 		i.environment = NewEnvironment(i.environment)
 		i.environment.Define("super", superclass)
 	}
 
-	methods := make(map[string]*LoxFunction)
-	for _, method := range stmt.Methods {
-		isInitializer := method.Name.Lexeme == "init"
-		function := NewLoxFunction(method, i.environment, isInitializer)
-		methods[method.Name.Lexeme] = function
-	}
-
 	class := &LoxClass{
 		Name:       stmt.Name.Lexeme,
-		Methods:    methods,
+		Methods:    make(map[string]Method),
 		Superclass: superclass,
 	}
+	for _, method := range stmt.Methods {
+		isInitializer := method.Name.Lexeme == "init"
+		function := NewLoxFunction(method, i.environment, isInitializer)
+		function.class = class
+		class.Methods[method.Name.Lexeme] = function
+	}
 
 	if stmt.Superclass != nil {
 		// Restore the previous environment
 		i.environment = i.environment.Enclosing
 	}
 
-	err := i.environment.Assign(stmt.Name, class)
+	// Each method's decorators run once, on the unbound method.
+	for _, method := range stmt.Methods {
+		decorators := methodDecorators[method.Name.Lexeme]
+		if len(decorators) == 0 {
+			continue
+		}
+		function := class.Methods[method.Name.Lexeme].(*LoxFunction)
+		value, err := i.decorate(decorators, &unboundMethod{method: function})
+		if err != nil {
+			return nil, err
+		}
+		decorated, err := newDecoratedMethod(decorators[0].at, function, value)
+		if err != nil {
+			return nil, err
+		}
+		class.Methods[method.Name.Lexeme] = decorated
+	}
+
+	err = i.environment.Assign(stmt.Name, class)
 	if err != nil {
 		return nil, err
 	}
 
+	if len(decorators) > 0 {
+		value, err := i.decorate(decorators, class)
+		if err != nil {
+			return nil, err
+		}
+		err = i.environment.Assign(stmt.Name, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return nil, nil
 }
 
+// evaluateCallee evaluates the callee of a call. A method call on an instance
+// tells the instance the property is being called, so a missing method goes
+// to methodMissing rather than propertyMissing.
+func (i *Interpreter) evaluateCallee(callee ast.Expr) (interface{}, error) {
+	get, ok := callee.(*ast.Get)
+	if !ok {
+		return i.evaluate(callee)
+	}
+
+	object, err := i.evaluate(get.Object)
+	if err != nil {
+		return nil, err
+	}
+	if instance, ok := object.(*LoxInstance); ok {
+		return instance.get(i, get.Name, true)
+	}
+	return i.getProperty(object, get.Name)
+}
+
 func (i *Interpreter) VisitGetExpr(expr *ast.Get) (interface{}, error) {
 	object, err := i.evaluate(expr.Object)
 	if err != nil {
 		return nil, err
 	}
 
-	if instance, ok := object.(*LoxInstance); ok {
-		return instance.Get(expr.Name)
+	return i.getProperty(object, expr.Name)
+}
+
+func (i *Interpreter) getProperty(object interface{}, name scanner.Token) (interface{}, error) {
+	if holder, ok := object.(PropertyHolder); ok {
+		return holder.Get(i, name)
 	}
 
 	return nil, &RuntimeError{
-		Token:   expr.Name,
+		Token:   name,
 		Message: "Only instances have properties.",
 	}
 }
@@ -415,12 +1133,15 @@ func (i *Interpreter) VisitSetExpr(expr *ast.Set) (interface{}, error) {
 		return nil, err
 	}
 
-	if instance, ok := object.(*LoxInstance); ok {
+	if setter, ok := object.(PropertySetter); ok {
 		value, err := i.evaluate(expr.Value)
 		if err != nil {
 			return nil, err
 		}
-		instance.Set(expr.Name, value)
+		err = setter.Set(i, expr.Name, value)
+		if err != nil {
+			return nil, err
+		}
 		return value, nil
 	}
 
@@ -435,7 +1156,7 @@ func (i *Interpreter) VisitThisExpr(expr *ast.This) (interface{}, error) {
 }
 
 func (i *Interpreter) VisitSuperExpr(expr *ast.Super) (interface{}, error) {
-	distance, ok := i.locals[expr]
+	distance, ok := i.locals.get(expr)
 	if !ok {
 		return nil, fmt.Errorf("Undefined 'super' expression.")
 	}
@@ -454,17 +1175,147 @@ func (i *Interpreter) VisitSuperExpr(expr *ast.Super) (interface{}, error) {
 	}
 	object := objectInterface.(*LoxInstance)
 
-	// Look up the method in the superclass
-	method := superclass.findMethod(expr.Method.Lexeme)
-	if method == nil {
-		return nil, &RuntimeError{
-			Token:   expr.Method,
-			Message: fmt.Sprintf("Undefined property '%s'.", expr.Method.Lexeme),
+	// Look up the method in the superclass and bind it to 'this' instance
+	method, ok, err := superclass.bindMethod(i, object, expr.Method.Lexeme)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, undefinedProperty(expr.Method)
+	}
+	return method, nil
+}
+
+func (i *Interpreter) VisitMatchStmt(stmt *ast.MatchStmt) (interface{}, error) {
+	subject, err := i.evaluate(stmt.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, matchCase := range stmt.Cases {
+		env, matched, err := i.matchCase(matchCase, subject)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			return i.executeBlock([]ast.Stmt{matchCase.Body}, env)
 		}
 	}
+	return nil, nil
+}
+
+func (i *Interpreter) VisitMatchExpr(expr *ast.Match) (interface{}, error) {
+	subject, err := i.evaluate(expr.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, matchCase := range expr.Cases {
+		env, matched, err := i.matchCase(matchCase, subject)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			previous := i.environment
+			i.environment = env
+			defer func() {
+				i.environment = previous
+			}()
+			return i.evaluate(matchCase.Value)
+		}
+	}
+	return nil, i.newRuntimeError(expr.Keyword, "No case matched the value.")
+}
+
+// matchCase tests each of the case's alternative patterns against value. When
+// one matches and the guard (if any) holds, it returns the environment holding
+// the case's bindings.
+func (i *Interpreter) matchCase(matchCase *ast.MatchCase, value interface{}) (*Environment, bool, error) {
+	previous := i.environment
+	defer func() {
+		i.environment = previous
+	}()
+
+	for _, pattern := range matchCase.Patterns {
+		env := NewEnvironment(previous)
+		i.environment = env
+		matched, err := i.matchPattern(pattern, value, env)
+		if err != nil {
+			return nil, false, err
+		}
+		if !matched {
+			continue
+		}
+
+		if matchCase.Guard != nil {
+			guard, err := i.evaluate(matchCase.Guard)
+			if err != nil {
+				return nil, false, err
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+		return env, true, nil
+	}
+	return nil, false, nil
+}
+
+func (i *Interpreter) matchPattern(pattern ast.Pattern, value interface{}, env *Environment) (bool, error) {
+	switch pat := pattern.(type) {
+	case *ast.LiteralPattern:
+		return isEqual(pat.Value, value), nil
+	case *ast.WildcardPattern:
+		return true, nil
+	case *ast.BindingPattern:
+		env.Define(pat.Name.Lexeme, value)
+		return true, nil
+	case *ast.ClassPattern:
+		classValue, err := i.evaluate(pat.Class)
+		if err != nil {
+			return false, err
+		}
+		class, ok := classValue.(*LoxClass)
+		if !ok {
+			return false, i.newRuntimeError(pat.Class.Name, "Class pattern must name a class.")
+		}
 
-	// Bind the method to 'this' instance
-	return method.bind(object), nil
+		instance, ok := value.(*LoxInstance)
+		if !ok || !instance.Class.isSubclassOf(class) {
+			return false, nil
+		}
+		if len(pat.Fields) == 0 {
+			return true, nil
+		}
+
+		// Fields are matched by the names of the initializer's parameters,
+		// which only an undecorated Lox initializer is sure to have.
+		initializer, _ := class.findMethod("init").(*LoxFunction)
+		if initializer == nil {
+			return false, i.newRuntimeError(pat.Paren,
+				fmt.Sprintf("Pattern for '%s' can't match fields without an undecorated Lox initializer.", class.Name))
+		}
+		if len(pat.Fields) > initializer.Arity() {
+			return false, i.newRuntimeError(pat.Paren,
+				fmt.Sprintf("Pattern for '%s' has more fields than its initializer has parameters.", class.Name))
+		}
+		for n, field := range pat.Fields {
+			name := initializer.Declaration.Params[n].Lexeme
+			fieldValue, ok := instance.field(name)
+			if !ok {
+				// The initializer stored the parameter under another name,
+				// or not at all.
+				return false, i.newRuntimeError(pat.Paren,
+					fmt.Sprintf("Pattern for '%s' matches field '%s', named after its initializer's parameter, but the instance has no such field.", class.Name, name))
+			}
+			matched, err := i.matchPattern(field, fieldValue, env)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return false, nil
 }
 
 func (i *Interpreter) executeBlock(statements []ast.Stmt, environment *Environment) (interface{}, error) {
@@ -547,6 +1398,18 @@ func isEqual(a, b interface{}) bool {
 	if a == nil {
 		return false
 	}
+	if tuple, ok := a.(*LoxTuple); ok {
+		other, ok := b.(*LoxTuple)
+		return ok && tuple.equals(other)
+	}
+	if set, ok := a.(*LoxSet); ok {
+		other, ok := b.(*LoxSet)
+		return ok && set.equals(other)
+	}
+	if instance, ok := a.(*LoxInstance); ok {
+		other, ok := b.(*LoxInstance)
+		return ok && instance.identity() == other.identity()
+	}
 	return a == b
 }
 
@@ -556,3 +1419,12 @@ func (i *Interpreter) newRuntimeError(token scanner.Token, message string) error
 		Message: message,
 	}
 }
+
+// newTypeError reports operands of the wrong type for an operator.
+func (i *Interpreter) newTypeError(token scanner.Token, message string) error {
+	return &RuntimeError{
+		Token:   token,
+		Message: message,
+		Code:    errors.CodeTypeMismatch,
+	}
+}
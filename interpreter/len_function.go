@@ -0,0 +1,31 @@
+package interpreter
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+type LenFunction struct{}
+
+func (l *LenFunction) Arity() int {
+	return 1
+}
+
+func (l *LenFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	switch value := arguments[0].(type) {
+	case string:
+		// Count characters, as for-in does, not bytes.
+		return float64(utf8.RuneCountInString(value)), nil
+	case *LoxList:
+		return float64(len(value.Elements)), nil
+	case *LoxTuple:
+		return float64(len(value.Elements)), nil
+	case *LoxSet:
+		return float64(len(value.elements)), nil
+	}
+	return nil, fmt.Errorf("Can only take the length of strings, lists, tuples and sets.")
+}
+
+func (l *LenFunction) String() string {
+	return "<native fn>"
+}
@@ -1,11 +1,26 @@
 package interpreter
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/chase-compton/LOX_GO/scanner"
+)
 
 type LoxClass struct {
     Name       string
-    Methods    map[string]*LoxFunction
+    Methods    map[string]Method
     Superclass *LoxClass
+
+    // properties are a native class's fields implemented in Go.
+    properties map[string]*NativeProperty
+
+    // builtin marks the classes type() returns for non-instances, such as
+    // Number, which can't be instantiated.
+    builtin bool
+
+    // construct creates values of a built-in type that can be called, such
+    // as Set.
+    construct Callable
 }
 
 func (c *LoxClass) String() string {
@@ -13,6 +28,9 @@ func (c *LoxClass) String() string {
 }
 
 func (c *LoxClass) Arity() int {
+    if c.construct != nil {
+        return c.construct.Arity()
+    }
     initializer := c.findMethod("init")
     if initializer != nil {
         return initializer.Arity()
@@ -21,20 +39,81 @@ func (c *LoxClass) Arity() int {
 }
 
 func (c *LoxClass) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+    if c.construct != nil {
+        return c.construct.Call(interpreter, arguments)
+    }
+    if c.builtin {
+        return nil, fmt.Errorf("Can't create instances of built-in type '%s'.", c.Name)
+    }
     instance := NewLoxInstance(c)
-    initializer := c.findMethod("init")
-    if initializer != nil {
-        initializer.bind(instance).Call(interpreter, arguments)
+    initializer, ok, err := c.bindMethod(interpreter, instance, "init")
+    if err != nil {
+        return nil, err
+    }
+    if ok {
+        callable, isCallable := initializer.(Callable)
+        if !isCallable {
+            return nil, fmt.Errorf("Decorated initializer must be callable.")
+        }
+        _, err = callable.Call(interpreter, arguments)
+        if err != nil {
+            return nil, err
+        }
     }
     return instance, nil
 }
 
-func (c *LoxClass) findMethod(name string) *LoxFunction {
-    if method, ok := c.Methods[name]; ok {
-        return method
+// Get looks up a property of the class itself. Only built-in types such as
+// Promise have any, which their constructors provide.
+func (c *LoxClass) Get(interpreter *Interpreter, name scanner.Token) (interface{}, error) {
+    if holder, ok := c.construct.(PropertyHolder); ok {
+        return holder.Get(interpreter, name)
+    }
+    return nil, &RuntimeError{
+        Token:   name,
+        Message: "Only instances have properties.",
+    }
+}
+
+func (c *LoxClass) findMethod(name string) Method {
+    for class := c; class != nil; class = class.Superclass {
+        if method, ok := class.Methods[name]; ok {
+            return method
+        }
+    }
+    return nil
+}
+
+// bindMethod binds the named method to instance. A method decorated into
+// something that can't be called is returned as it is.
+func (c *LoxClass) bindMethod(interpreter *Interpreter, instance *LoxInstance, name string) (interface{}, bool, error) {
+    method := c.findMethod(name)
+    if method == nil {
+        return nil, false, nil
     }
-    if c.Superclass != nil {
-        return c.Superclass.findMethod(name)
+    if decorated, ok := method.(*decoratedMethod); ok {
+        if _, callable := decorated.value.(Callable); !callable {
+            return decorated.value, true, nil
+        }
+    }
+    return method.Bind(instance), true, nil
+}
+
+// findProperty looks up a native property on the class or its ancestors.
+func (c *LoxClass) findProperty(name string) *NativeProperty {
+    for class := c; class != nil; class = class.Superclass {
+        if property, ok := class.properties[name]; ok {
+            return property
+        }
     }
     return nil
 }
+
+func (c *LoxClass) isSubclassOf(other *LoxClass) bool {
+    for class := c; class != nil; class = class.Superclass {
+        if class == other {
+            return true
+        }
+    }
+    return false
+}
@@ -8,6 +8,8 @@ type LoxFunction struct {
 	Declaration   *ast.FunctionStmt
 	Closure       *Environment
 	IsInitializer bool
+	// class is the class declaring this function if it is a method.
+	class *LoxClass
 }
 
 func NewLoxFunction(declaration *ast.FunctionStmt, closure *Environment, isInitializer bool) *LoxFunction {
@@ -23,30 +25,121 @@ func (f *LoxFunction) Arity() int {
 }
 
 func (f *LoxFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
-	environment := NewEnvironment(f.Closure)
-	for i, param := range f.Declaration.Params {
-		environment.Define(param.Lexeme, arguments[i])
+	if f.Declaration.IsAsync {
+		return f.callAsync(interpreter, arguments), nil
 	}
+	return f.call(interpreter, arguments)
+}
+
+// callAsync starts the function as a coroutine and returns a promise for its
+// result. The body runs synchronously up to its first await.
+func (f *LoxFunction) callAsync(interpreter *Interpreter, arguments []interface{}) *LoxPromise {
+	promise := newPromise(interpreter.loop)
+	coroutine := newFiber(&asyncBody{function: f, arguments: arguments}, interpreter)
+	coroutine.async = true
+	stepAsync(coroutine, promise)
+	return promise
+}
 
-	var returnValue interface{}
-	err := interpreter.executeBlockWithReturn(f.Declaration.Body, environment, &returnValue)
+// stepAsync resumes an async call until it awaits or finishes. An await
+// yields the awaited promise, and the call is stepped again once it settles.
+func stepAsync(coroutine *LoxFiber, promise *LoxPromise) {
+	value, err := coroutine.resume(nil)
 	if err != nil {
-		if returnErr, ok := err.(*Return); ok {
-			if f.IsInitializer {
-				// Return 'this' from initializer
-				return f.Closure.GetAt(0, "this")
+		promise.rejectWithError(err)
+		return
+	}
+	if coroutine.state == fiberDone {
+		promise.resolve(value)
+		return
+	}
+
+	awaited := value.(*LoxPromise)
+	awaited.subscribe(func(interface{}) {
+		stepAsync(coroutine, promise)
+	}, func(interface{}, error) {
+		stepAsync(coroutine, promise)
+	})
+}
+
+type asyncBody struct {
+	function  *LoxFunction
+	arguments []interface{}
+}
+
+func (b *asyncBody) Arity() int {
+	return 0
+}
+
+func (b *asyncBody) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	return b.function.call(interpreter, b.arguments)
+}
+
+// call runs the function body. A body that ends in a tail call returns a
+// TailCall instead of making the call, and call loops to run the callee in
+// this same Go frame: a trampoline.
+func (f *LoxFunction) call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	line := interpreter.takeCallLine()
+	interpreter.pushFrame(f.frame(line))
+	defer interpreter.popFrame()
+
+	for {
+		environment := NewEnvironment(f.Closure)
+		for i, param := range f.Declaration.Params {
+			environment.Define(param.Lexeme, arguments[i])
+		}
+
+		if err := interpreter.checkRequires(f, environment); err != nil {
+			interpreter.recordFrames(err)
+			return nil, err
+		}
+
+		var returnValue interface{}
+		err := interpreter.executeBlockWithReturn(f.Declaration.Body, environment, &returnValue)
+		if err != nil {
+			returnErr, ok := err.(*Return)
+			if !ok {
+				interpreter.recordFrames(err)
+				return nil, err
+			}
+			if returnErr.TailCall != nil {
+				// The callee takes over this call's frame, including the
+				// line it was originally called from.
+				f = returnErr.TailCall.Function
+				arguments = returnErr.TailCall.Arguments
+				interpreter.popFrame()
+				interpreter.pushFrame(f.frame(line))
+				continue
 			}
-			return returnErr.Value, nil
 		}
-		return nil, err
+
+		if f.IsInitializer {
+			// An initializer returns 'this', whether or not it returns
+			// explicitly.
+			returnValue, err = f.Closure.GetAt(0, "this")
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if err := interpreter.checkEnsures(f, environment, returnValue); err != nil {
+			interpreter.recordFrames(err)
+			return nil, err
+		}
+		return returnValue, nil
 	}
+}
 
-	if f.IsInitializer {
-		// Return 'this' if no explicit return in initializer
-		return f.Closure.GetAt(0, "this")
+func (f *LoxFunction) frame(line int) CallFrame {
+	frame := CallFrame{Function: f.Declaration.Name.Lexeme, Line: line}
+	if f.class != nil {
+		frame.Class = f.class.Name
 	}
+	return frame
+}
 
-	return nil, nil
+func (f *LoxFunction) Bind(instance *LoxInstance) Callable {
+	return f.bind(instance)
 }
 
 func (f *LoxFunction) bind(instance *LoxInstance) *LoxFunction {
@@ -56,5 +149,6 @@ func (f *LoxFunction) bind(instance *LoxInstance) *LoxFunction {
 		Declaration:   f.Declaration,
 		Closure:       env,
 		IsInitializer: f.IsInitializer,
+		class:         f.class,
 	}
 }
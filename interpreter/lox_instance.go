@@ -2,6 +2,8 @@ package interpreter
 
 import (
 	"fmt"
+	"reflect"
+	"sync"
 
 	"github.com/chase-compton/LOX_GO/scanner"
 )
@@ -9,6 +11,21 @@ import (
 type LoxInstance struct {
     Class  *LoxClass
     Fields map[string]interface{}
+    hooks  map[activeHook]bool
+    // state is Go data a native class keeps on the instance, out of reach
+    // of Lox code.
+    state interface{}
+    mu    sync.RWMutex
+}
+
+// activeHook records that an interpreter is running one of an instance's
+// hook methods. While it is, that hook doesn't fire again for the instance
+// on that interpreter, so a hook that touches 'this' can't recurse forever.
+// Keying by interpreter keeps tasks and fibers, which each have their own,
+// from seeing each other's hooks as active.
+type activeHook struct {
+    hook        string
+    interpreter *Interpreter
 }
 
 func NewLoxInstance(class *LoxClass) *LoxInstance {
@@ -22,22 +39,215 @@ func (li *LoxInstance) String() string {
     return fmt.Sprintf("<%s instance>", li.Class.Name)
 }
 
-func (li *LoxInstance) Get(name scanner.Token) (interface{}, error) {
-    if value, ok := li.Fields[name.Lexeme]; ok {
+func (li *LoxInstance) Get(interpreter *Interpreter, name scanner.Token) (interface{}, error) {
+    return li.get(interpreter, name, false)
+}
+
+// get looks up a field or method. When neither exists it falls back to the
+// class's methodMissing and propertyMissing hooks, preferring methodMissing
+// when the property is about to be called.
+func (li *LoxInstance) get(interpreter *Interpreter, name scanner.Token, forCall bool) (interface{}, error) {
+    if property := li.Class.findProperty(name.Lexeme); property != nil {
+        value, err := property.Get(li)
+        if err != nil {
+            return nil, &RuntimeError{Token: name, Message: err.Error()}
+        }
         return value, nil
     }
+    if value, ok := li.field(name.Lexeme); ok {
+        return value, nil
+    }
+
+    method, ok, err := li.Class.bindMethod(interpreter, li, name.Lexeme)
+    if err != nil {
+        return nil, err
+    }
+    if ok {
+        return method, nil
+    }
 
-    method := li.Class.findMethod(name.Lexeme)
-    if method != nil {
-        return method.bind(li), nil
+    if forCall && li.hasHook(interpreter, "methodMissing") {
+        return &missingMethod{instance: li, name: name}, nil
+    }
+    value, ok, err := li.callHook(interpreter, "propertyMissing", name, name.Lexeme)
+    if ok || err != nil {
+        return value, err
+    }
+    if li.hasHook(interpreter, "methodMissing") {
+        return &missingMethod{instance: li, name: name}, nil
     }
 
-    return nil, &RuntimeError{
-        Token:   name,
-        Message: fmt.Sprintf("Undefined property '%s'.", name.Lexeme),
+    return nil, undefinedProperty(name, li.propertyNames()...)
+}
+
+// propertyNames lists the fields and methods, inherited ones included, that
+// the instance responds to.
+func (li *LoxInstance) propertyNames() []string {
+    names := li.fieldNames()
+    for class := li.Class; class != nil; class = class.Superclass {
+        for name := range class.Methods {
+            names = append(names, name)
+        }
     }
+    return names
 }
 
-func (li *LoxInstance) Set(name scanner.Token, value interface{}) {
+// Set stores a field, or hands it to the class's onSet hook if it has one.
+// Native properties are set by their Set functions instead.
+// The hook decides whether to store the value; assignments to 'this' inside
+// the hook store fields directly.
+func (li *LoxInstance) Set(interpreter *Interpreter, name scanner.Token, value interface{}) error {
+    if property := li.Class.findProperty(name.Lexeme); property != nil {
+        if property.Set == nil {
+            return &RuntimeError{Token: name, Message: fmt.Sprintf("Property '%s' is read-only.", name.Lexeme)}
+        }
+        if err := property.Set(li, value); err != nil {
+            return &RuntimeError{Token: name, Message: err.Error()}
+        }
+        return nil
+    }
+
+    _, ok, err := li.callHook(interpreter, "onSet", name, name.Lexeme, value)
+    if ok || err != nil {
+        return err
+    }
+
+    li.mu.Lock()
+    defer li.mu.Unlock()
     li.Fields[name.Lexeme] = value
+    return nil
+}
+
+// hasHook reports whether the class defines hook and it isn't already
+// running for this instance on interpreter.
+func (li *LoxInstance) hasHook(interpreter *Interpreter, hook string) bool {
+    if li.Class.findMethod(hook) == nil {
+        return false
+    }
+    li.mu.RLock()
+    defer li.mu.RUnlock()
+    return !li.hooks[activeHook{hook, interpreter}]
+}
+
+// callHook calls the hook method with arguments if hasHook allows it. The
+// returned bool reports whether the hook ran.
+func (li *LoxInstance) callHook(interpreter *Interpreter, hook string, name scanner.Token, arguments ...interface{}) (interface{}, bool, error) {
+    if !li.hasHook(interpreter, hook) {
+        return nil, false, nil
+    }
+
+    method, _, err := li.Class.bindMethod(interpreter, li, hook)
+    if err != nil {
+        return nil, true, err
+    }
+    callable, ok := method.(Callable)
+    if !ok || (callable.Arity() != len(arguments) && callable.Arity() != VariadicArity) {
+        return nil, true, &RuntimeError{
+            Token:   name,
+            Message: fmt.Sprintf("Hook '%s' must be a method taking %d parameters.", hook, len(arguments)),
+        }
+    }
+
+    key := activeHook{hook, interpreter}
+    li.mu.Lock()
+    if li.hooks == nil {
+        li.hooks = make(map[activeHook]bool)
+    }
+    li.hooks[key] = true
+    li.mu.Unlock()
+    defer func() {
+        li.mu.Lock()
+        delete(li.hooks, key)
+        li.mu.Unlock()
+    }()
+
+    value, err := interpreter.call(name, callable, arguments)
+    return value, true, err
+}
+
+// State returns the Go data a native class stored with SetState, or nil.
+func (li *LoxInstance) State() interface{} {
+    li.mu.RLock()
+    defer li.mu.RUnlock()
+    return li.state
+}
+
+// SetState stores Go data on the instance for a native class's methods. Lox
+// code can't see it as a field. Instances whose state is the same pointer
+// are the same object to Lox: they're equal, and count once in a set.
+func (li *LoxInstance) SetState(state interface{}) {
+    li.mu.Lock()
+    defer li.mu.Unlock()
+    li.state = state
+}
+
+// identity returns what makes the instance itself: the pointer in its
+// state, if it has one, or else the instance.
+func (li *LoxInstance) identity() interface{} {
+    state := li.State()
+    if state != nil && reflect.TypeOf(state).Kind() == reflect.Pointer {
+        return state
+    }
+    return li
+}
+
+func (li *LoxInstance) field(name string) (interface{}, bool) {
+    li.mu.RLock()
+    defer li.mu.RUnlock()
+    value, ok := li.Fields[name]
+    return value, ok
+}
+
+// fieldNames lists the instance's fields, native properties included.
+func (li *LoxInstance) fieldNames() []string {
+    li.mu.RLock()
+    defer li.mu.RUnlock()
+    var names []string
+    for class := li.Class; class != nil; class = class.Superclass {
+        for name := range class.properties {
+            names = append(names, name)
+        }
+    }
+    for name := range li.Fields {
+        names = append(names, name)
+    }
+    return names
+}
+
+func (li *LoxInstance) deleteField(name string) bool {
+    li.mu.Lock()
+    defer li.mu.Unlock()
+    if _, ok := li.Fields[name]; !ok {
+        return false
+    }
+    delete(li.Fields, name)
+    return true
+}
+
+// missingMethod is what an instance returns for a property its class's
+// methodMissing hook will handle. Calling it calls the hook with the
+// property name and the arguments as a list.
+type missingMethod struct {
+    instance *LoxInstance
+    name     scanner.Token
+}
+
+func (m *missingMethod) Arity() int {
+    return VariadicArity
+}
+
+func (m *missingMethod) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+    list := NewLoxList(append([]interface{}{}, arguments...))
+    value, ok, err := m.instance.callHook(interpreter, "methodMissing", m.name, m.name.Lexeme, list)
+    if !ok {
+        return nil, &RuntimeError{
+            Token:   m.name,
+            Message: fmt.Sprintf("Undefined property '%s'.", m.name.Lexeme),
+        }
+    }
+    return value, err
+}
+
+func (m *missingMethod) String() string {
+    return fmt.Sprintf("<fn %s>", m.name.Lexeme)
 }
@@ -0,0 +1,19 @@
+package interpreter
+
+import "strings"
+
+type LoxList struct {
+	Elements []interface{}
+}
+
+func NewLoxList(elements []interface{}) *LoxList {
+	return &LoxList{Elements: elements}
+}
+
+func (l *LoxList) String() string {
+	parts := make([]string, len(l.Elements))
+	for n, element := range l.Elements {
+		parts[n] = stringify(element)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
@@ -0,0 +1,257 @@
+package interpreter
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/chase-compton/LOX_GO/scanner"
+)
+
+// LoxSet is an unordered collection of distinct values, where "distinct" has
+// the same meaning as Lox's `==`. Elements remember their insertion order so
+// that printing and iteration are predictable.
+type LoxSet struct {
+	elements []interface{}
+	buckets  map[interface{}][]interface{}
+}
+
+func NewLoxSet(elements []interface{}) (*LoxSet, error) {
+	set := &LoxSet{buckets: make(map[interface{}][]interface{})}
+	for _, element := range elements {
+		if _, err := set.add(element); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// setKey picks the bucket a value lives in. Values that compare by identity
+// or by primitive value are their own key, instances wrapping a Go pointer
+// are keyed by the pointer, and a tuple's key is built from its elements'
+// keys, so equal values always share a bucket. Sets share one bucket, since
+// their keys would depend on the order elements were added. Values in a
+// bucket are told apart with isEqual. It returns false for values that
+// can't be keys, such as a Go func an embedder passed in.
+func setKey(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case *LoxTuple:
+		var key tupleKey
+		for n := len(v.Elements) - 1; n >= 0; n-- {
+			element, ok := setKey(v.Elements[n])
+			if !ok {
+				return nil, false
+			}
+			key = tupleKey{element: element, rest: key}
+		}
+		return key, true
+	case *LoxSet:
+		return "set", true
+	case *LoxInstance:
+		return v.identity(), true
+	}
+	return value, value == nil || reflect.ValueOf(value).Comparable()
+}
+
+// tupleKey is the key of a tuple's elements from one of them on: that
+// element's key and the tupleKey of the rest.
+type tupleKey struct {
+	element interface{}
+	rest    interface{}
+}
+
+func (s *LoxSet) has(value interface{}) bool {
+	key, ok := setKey(value)
+	return ok && s.inBucket(key, value)
+}
+
+func (s *LoxSet) inBucket(key, value interface{}) bool {
+	for _, element := range s.buckets[key] {
+		if isEqual(element, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *LoxSet) add(value interface{}) (bool, error) {
+	key, ok := setKey(value)
+	if !ok {
+		return false, fmt.Errorf("Can't add %s to a set, since it can't be compared.", stringify(value))
+	}
+	if s.inBucket(key, value) {
+		return false, nil
+	}
+	s.buckets[key] = append(s.buckets[key], value)
+	s.elements = append(s.elements, value)
+	return true, nil
+}
+
+func (s *LoxSet) remove(value interface{}) bool {
+	key, ok := setKey(value)
+	if !ok {
+		return false
+	}
+	bucket := s.buckets[key]
+	for n, element := range bucket {
+		if isEqual(element, value) {
+			s.buckets[key] = append(bucket[:n:n], bucket[n+1:]...)
+			if len(s.buckets[key]) == 0 {
+				delete(s.buckets, key)
+			}
+			s.elements = removeEqual(s.elements, value)
+			return true
+		}
+	}
+	return false
+}
+
+func removeEqual(elements []interface{}, value interface{}) []interface{} {
+	for n, element := range elements {
+		if isEqual(element, value) {
+			return append(elements[:n:n], elements[n+1:]...)
+		}
+	}
+	return elements
+}
+
+func (s *LoxSet) equals(other *LoxSet) bool {
+	if len(s.elements) != len(other.elements) {
+		return false
+	}
+	for _, element := range s.elements {
+		if !other.has(element) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *LoxSet) Elements() []interface{} {
+	return append([]interface{}{}, s.elements...)
+}
+
+func (s *LoxSet) String() string {
+	parts := make([]string, len(s.elements))
+	for n, element := range s.elements {
+		parts[n] = stringify(element)
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func (s *LoxSet) Get(interpreter *Interpreter, name scanner.Token) (interface{}, error) {
+	method := func(arity int, fn func(arguments []interface{}) (interface{}, error)) *NativeMethod {
+		return &NativeMethod{
+			Name:  name.Lexeme,
+			arity: arity,
+			fn: func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+				return fn(arguments)
+			},
+		}
+	}
+	other := func(arguments []interface{}) (*LoxSet, error) {
+		set, ok := arguments[0].(*LoxSet)
+		if !ok {
+			return nil, fmt.Errorf("Argument to '%s' must be a set.", name.Lexeme)
+		}
+		return set, nil
+	}
+
+	switch name.Lexeme {
+	case "add":
+		return method(1, func(arguments []interface{}) (interface{}, error) {
+			return s.add(arguments[0])
+		}), nil
+	case "remove":
+		return method(1, func(arguments []interface{}) (interface{}, error) {
+			return s.remove(arguments[0]), nil
+		}), nil
+	case "has":
+		return method(1, func(arguments []interface{}) (interface{}, error) {
+			return s.has(arguments[0]), nil
+		}), nil
+	case "len":
+		return method(0, func(arguments []interface{}) (interface{}, error) {
+			return float64(len(s.elements)), nil
+		}), nil
+	case "union":
+		return method(1, func(arguments []interface{}) (interface{}, error) {
+			o, err := other(arguments)
+			if err != nil {
+				return nil, err
+			}
+			return NewLoxSet(append(s.Elements(), o.elements...))
+		}), nil
+	case "intersect":
+		return method(1, func(arguments []interface{}) (interface{}, error) {
+			o, err := other(arguments)
+			if err != nil {
+				return nil, err
+			}
+			var elements []interface{}
+			for _, element := range s.elements {
+				if o.has(element) {
+					elements = append(elements, element)
+				}
+			}
+			return NewLoxSet(elements)
+		}), nil
+	case "difference":
+		return method(1, func(arguments []interface{}) (interface{}, error) {
+			o, err := other(arguments)
+			if err != nil {
+				return nil, err
+			}
+			var elements []interface{}
+			for _, element := range s.elements {
+				if !o.has(element) {
+					elements = append(elements, element)
+				}
+			}
+			return NewLoxSet(elements)
+		}), nil
+	}
+	return nil, undefinedProperty(name)
+}
+
+// SetFunction is the `Set(a, b, ...)` constructor, called through the
+// built-in Set type.
+type SetFunction struct{}
+
+func (f *SetFunction) Arity() int {
+	return VariadicArity
+}
+
+func (f *SetFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	return NewLoxSet(arguments)
+}
+
+func (f *SetFunction) String() string {
+	return "<native fn>"
+}
+
+// SetFromFunction is `set()`, which builds an empty set or collects the
+// elements of any iterable value.
+type SetFromFunction struct{}
+
+func (f *SetFromFunction) Arity() int {
+	return VariadicArity
+}
+
+func (f *SetFromFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	if len(arguments) > 1 {
+		return nil, fmt.Errorf("Expected at most 1 argument but got %d.", len(arguments))
+	}
+	if len(arguments) == 0 {
+		return NewLoxSet(nil)
+	}
+	elements, ok := iterate(arguments[0])
+	if !ok {
+		return nil, fmt.Errorf("Can only build a set from an iterable value.")
+	}
+	return NewLoxSet(elements)
+}
+
+func (f *SetFromFunction) String() string {
+	return "<native fn>"
+}
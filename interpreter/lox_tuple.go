@@ -0,0 +1,36 @@
+package interpreter
+
+import "strings"
+
+// LoxTuple is an immutable, fixed-length sequence of values. Unlike lists,
+// tuples compare equal when their elements do.
+type LoxTuple struct {
+	Elements []interface{}
+}
+
+func NewLoxTuple(elements []interface{}) *LoxTuple {
+	return &LoxTuple{Elements: elements}
+}
+
+func (t *LoxTuple) String() string {
+	parts := make([]string, len(t.Elements))
+	for n, element := range t.Elements {
+		parts[n] = stringify(element)
+	}
+	if len(parts) == 1 {
+		return "(" + parts[0] + ",)"
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+func (t *LoxTuple) equals(other *LoxTuple) bool {
+	if len(t.Elements) != len(other.Elements) {
+		return false
+	}
+	for n, element := range t.Elements {
+		if !isEqual(element, other.Elements[n]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package interpreter
+
+import "fmt"
+
+// NativeFunc implements a method of a native class in Go. this is the
+// instance the method was called on, which may be an instance of a Lox
+// subclass.
+type NativeFunc func(interpreter *Interpreter, this *LoxInstance, arguments []interface{}) (interface{}, error)
+
+// NativeFunction is a method implemented in Go. It can sit in a class's
+// Methods next to LoxFunctions, and is bound and inherited the same way.
+type NativeFunction struct {
+	Name  string
+	arity int
+	fn    NativeFunc
+}
+
+// NewNativeFunction returns a method taking arity arguments, or any number
+// for VariadicArity.
+func NewNativeFunction(name string, arity int, fn NativeFunc) *NativeFunction {
+	return &NativeFunction{Name: name, arity: arity, fn: fn}
+}
+
+func (f *NativeFunction) Arity() int {
+	return f.arity
+}
+
+func (f *NativeFunction) Bind(instance *LoxInstance) Callable {
+	return &NativeMethod{
+		Name:  f.Name,
+		arity: f.arity,
+		fn: func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+			return f.fn(interpreter, instance, arguments)
+		},
+	}
+}
+
+func (f *NativeFunction) String() string {
+	return fmt.Sprintf("<native fn %s>", f.Name)
+}
+
+// NativeProperty is a property of a native class's instances implemented in
+// Go. Lox code reads and assigns it like a field, and it is inherited like
+// a method. A nil Set makes it read-only.
+type NativeProperty struct {
+	Name string
+	Get  func(this *LoxInstance) (interface{}, error)
+	Set  func(this *LoxInstance, value interface{}) error
+}
+
+// NewNativeClass returns a class whose methods are implemented in Go. It is
+// an ordinary LoxClass, so Lox code can call it to create instances and
+// subclass it with `class Foo < Native`, overriding its methods and calling
+// them through super. An "init" method runs when an instance is created and
+// typically keeps Go data on the instance with SetState.
+func NewNativeClass(name string, superclass *LoxClass, methods ...*NativeFunction) *LoxClass {
+	class := &LoxClass{
+		Name:       name,
+		Methods:    make(map[string]Method, len(methods)),
+		Superclass: superclass,
+	}
+	for _, method := range methods {
+		class.Methods[method.Name] = method
+	}
+	return class
+}
+
+// DefineProperty adds a native property to a class, replacing any of the
+// same name.
+func (c *LoxClass) DefineProperty(property *NativeProperty) {
+	if c.properties == nil {
+		c.properties = make(map[string]*NativeProperty)
+	}
+	c.properties[property.Name] = property
+}
@@ -0,0 +1,48 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/chase-compton/LOX_GO/errors"
+	"github.com/chase-compton/LOX_GO/scanner"
+)
+
+// PropertyHolder is implemented by values that respond to `value.name`.
+// LoxInstance is one; native values such as sets expose their methods the
+// same way.
+type PropertyHolder interface {
+	Get(interpreter *Interpreter, name scanner.Token) (interface{}, error)
+}
+
+// PropertySetter is implemented by values that accept `value.name = x`.
+type PropertySetter interface {
+	Set(interpreter *Interpreter, name scanner.Token, value interface{}) error
+}
+
+// NativeMethod is a Go function bound to a native receiver.
+type NativeMethod struct {
+	Name  string
+	arity int
+	fn    func(interpreter *Interpreter, arguments []interface{}) (interface{}, error)
+}
+
+func (m *NativeMethod) Arity() int {
+	return m.arity
+}
+
+func (m *NativeMethod) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	return m.fn(interpreter, arguments)
+}
+
+func (m *NativeMethod) String() string {
+	return fmt.Sprintf("<native method %s>", m.Name)
+}
+
+func undefinedProperty(name scanner.Token, candidates ...string) error {
+	return &RuntimeError{
+		Token:   name,
+		Message: fmt.Sprintf("Undefined property '%s'.", name.Lexeme),
+		Code:    errors.CodeUndefinedProperty,
+		Help:    errors.DidYouMean(name.Lexeme, candidates),
+	}
+}
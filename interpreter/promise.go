@@ -0,0 +1,284 @@
+package interpreter
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/chase-compton/LOX_GO/scanner"
+)
+
+type promiseState int
+
+const (
+	promisePending promiseState = iota
+	promiseFulfilled
+	promiseRejected
+)
+
+// LoxPromise is the eventual result of an asynchronous operation. Handlers
+// attached with then/catch always run as microtasks on the event loop, never
+// synchronously, even if the promise has already settled.
+type LoxPromise struct {
+	loop  *EventLoop
+	mu    sync.Mutex
+	state promiseState
+	value interface{}
+	// cause is the runtime error that rejected the promise, if any.
+	cause     error
+	handled   bool
+	reactions []func()
+}
+
+func newPromise(loop *EventLoop) *LoxPromise {
+	return &LoxPromise{loop: loop}
+}
+
+func (p *LoxPromise) String() string {
+	return "<promise>"
+}
+
+func (p *LoxPromise) isSettled() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state != promisePending
+}
+
+func (p *LoxPromise) isHandled() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.handled
+}
+
+// resolve fulfills the promise with value, or makes it follow value if value
+// is itself a promise.
+func (p *LoxPromise) resolve(value interface{}) {
+	if other, ok := value.(*LoxPromise); ok {
+		if other == p {
+			p.reject("A promise cannot be resolved with itself.", nil)
+			return
+		}
+		other.subscribe(p.resolve, p.reject)
+		return
+	}
+	p.settle(promiseFulfilled, value, nil)
+}
+
+func (p *LoxPromise) reject(reason interface{}, cause error) {
+	if p.settle(promiseRejected, reason, cause) {
+		p.loop.trackRejection(p)
+	}
+}
+
+// rejectWithError rejects the promise because Lox code raised err. Handlers
+// receive the error message as the reason.
+func (p *LoxPromise) rejectWithError(err error) {
+	p.reject(err.Error(), err)
+}
+
+func (p *LoxPromise) settle(state promiseState, value interface{}, cause error) bool {
+	p.mu.Lock()
+	if p.state != promisePending {
+		p.mu.Unlock()
+		return false
+	}
+	p.state = state
+	p.value = value
+	p.cause = cause
+	reactions := p.reactions
+	p.reactions = nil
+	p.mu.Unlock()
+
+	for _, reaction := range reactions {
+		reaction()
+	}
+	return true
+}
+
+// subscribe arranges for one of the callbacks to run as a microtask once the
+// promise settles.
+func (p *LoxPromise) subscribe(onFulfilled func(interface{}), onRejected func(interface{}, error)) {
+	reaction := func() {
+		p.loop.enqueue(func() error {
+			if p.state == promiseFulfilled {
+				onFulfilled(p.value)
+			} else {
+				onRejected(p.value, p.cause)
+			}
+			return nil
+		})
+	}
+
+	p.mu.Lock()
+	p.handled = true
+	if p.state == promisePending {
+		p.reactions = append(p.reactions, reaction)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	reaction()
+}
+
+// then returns a promise for the result of passing this promise's outcome to
+// the matching handler. A missing handler passes the outcome straight through.
+func (p *LoxPromise) then(interpreter *Interpreter, onFulfilled, onRejected Callable) *LoxPromise {
+	next := newPromise(p.loop)
+	handle := func(handler Callable, value interface{}) {
+		result, err := callWithOptionalArgument(interpreter, handler, value)
+		if err != nil {
+			next.rejectWithError(err)
+			return
+		}
+		next.resolve(result)
+	}
+
+	p.subscribe(func(value interface{}) {
+		if onFulfilled == nil {
+			next.resolve(value)
+			return
+		}
+		handle(onFulfilled, value)
+	}, func(reason interface{}, cause error) {
+		if onRejected == nil {
+			next.reject(reason, cause)
+			return
+		}
+		handle(onRejected, reason)
+	})
+	return next
+}
+
+// rejectionError is the error raised by awaiting a rejected promise.
+func (p *LoxPromise) rejectionError(token scanner.Token) error {
+	if p.cause != nil {
+		return p.cause
+	}
+	return &RuntimeError{
+		Token:   token,
+		Message: fmt.Sprintf("Promise rejected with %s.", stringify(p.value)),
+	}
+}
+
+func (p *LoxPromise) Get(interpreter *Interpreter, name scanner.Token) (interface{}, error) {
+	switch name.Lexeme {
+	case "then":
+		return &NativeMethod{Name: "then", arity: VariadicArity, fn: func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+			if len(arguments) < 1 || len(arguments) > 2 {
+				return nil, fmt.Errorf("Expected 1 or 2 arguments but got %d.", len(arguments))
+			}
+			handlers, err := promiseHandlers(arguments)
+			if err != nil {
+				return nil, err
+			}
+			var onRejected Callable
+			if len(handlers) == 2 {
+				onRejected = handlers[1]
+			}
+			return p.then(interpreter, handlers[0], onRejected), nil
+		}}, nil
+	case "catch":
+		return &NativeMethod{Name: "catch", arity: 1, fn: func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+			handlers, err := promiseHandlers(arguments)
+			if err != nil {
+				return nil, err
+			}
+			return p.then(interpreter, nil, handlers[0]), nil
+		}}, nil
+	}
+	return nil, undefinedProperty(name)
+}
+
+func promiseHandlers(arguments []interface{}) ([]Callable, error) {
+	handlers := make([]Callable, len(arguments))
+	for n, argument := range arguments {
+		handler, ok := argument.(Callable)
+		if !ok || handler.Arity() > 1 {
+			return nil, fmt.Errorf("Promise handlers must be functions taking at most one parameter.")
+		}
+		handlers[n] = handler
+	}
+	return handlers, nil
+}
+
+func callWithOptionalArgument(interpreter *Interpreter, function Callable, value interface{}) (interface{}, error) {
+	if function.Arity() == 0 {
+		return function.Call(interpreter, nil)
+	}
+	return function.Call(interpreter, []interface{}{value})
+}
+
+// promiseSettler is the resolve or reject function handed to a Promise
+// executor.
+type promiseSettler struct {
+	promise *LoxPromise
+	reject  bool
+}
+
+func (s *promiseSettler) Arity() int {
+	return VariadicArity
+}
+
+func (s *promiseSettler) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	value, err := optionalArgument(arguments)
+	if err != nil {
+		return nil, err
+	}
+	if s.reject {
+		s.promise.reject(value, nil)
+	} else {
+		s.promise.resolve(value)
+	}
+	return nil, nil
+}
+
+func (s *promiseSettler) String() string {
+	return "<native fn>"
+}
+
+// PromiseClass is behind the built-in Promise type. `Promise(executor)`
+// calls executor(resolve, reject) immediately; Promise.resolve(value) and
+// Promise.reject(reason) build already-settled promises.
+type PromiseClass struct{}
+
+func (c *PromiseClass) Arity() int {
+	return 1
+}
+
+func (c *PromiseClass) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	executor, ok := arguments[0].(Callable)
+	if !ok || executor.Arity() != 2 {
+		return nil, fmt.Errorf("Promise executor must be a function taking resolve and reject.")
+	}
+
+	promise := newPromise(interpreter.loop)
+	_, err := executor.Call(interpreter, []interface{}{
+		&promiseSettler{promise: promise},
+		&promiseSettler{promise: promise, reject: true},
+	})
+	if err != nil {
+		promise.rejectWithError(err)
+	}
+	return promise, nil
+}
+
+func (c *PromiseClass) Get(interpreter *Interpreter, name scanner.Token) (interface{}, error) {
+	switch name.Lexeme {
+	case "resolve":
+		return &NativeMethod{Name: "resolve", arity: 1, fn: func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+			promise := newPromise(interpreter.loop)
+			promise.resolve(arguments[0])
+			return promise, nil
+		}}, nil
+	case "reject":
+		return &NativeMethod{Name: "reject", arity: 1, fn: func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+			promise := newPromise(interpreter.loop)
+			promise.reject(arguments[0], nil)
+			return promise, nil
+		}}, nil
+	}
+	return nil, undefinedProperty(name)
+}
+
+func (c *PromiseClass) String() string {
+	return "<class Promise>"
+}
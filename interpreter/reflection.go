@@ -0,0 +1,340 @@
+package interpreter
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/chase-compton/LOX_GO/scanner"
+)
+
+// Built-in types are what type() returns for values that aren't instances of
+// a Lox class, and are globals of the same names. They behave like classes
+// with no methods, except that they can't be subclassed and most can't be
+// called; those that can, such as Set, are their values' constructors.
+var (
+	numberType   = newBuiltinType("Number")
+	stringType   = newBuiltinType("String")
+	booleanType  = newBuiltinType("Boolean")
+	nilType      = newBuiltinType("Nil")
+	listType     = newBuiltinType("List")
+	tupleType    = newBuiltinType("Tuple")
+	setType      = newConstructibleType("Set", &SetFunction{})
+	classType    = newBuiltinType("Class")
+	functionType = newBuiltinType("Function")
+	fiberType    = newConstructibleType("Fiber", &FiberClass{})
+	taskType     = newBuiltinType("Task")
+	channelType  = newConstructibleType("Channel", &ChannelFunction{})
+	mutexType    = newConstructibleType("Mutex", &MutexFunction{})
+	promiseType  = newConstructibleType("Promise", &PromiseClass{})
+)
+
+var builtinTypes = []*LoxClass{
+	numberType, stringType, booleanType, nilType, listType, tupleType, setType,
+	classType, functionType, fiberType, taskType, channelType, mutexType, promiseType,
+}
+
+func newBuiltinType(name string) *LoxClass {
+	return &LoxClass{Name: name, Methods: map[string]Method{}, builtin: true}
+}
+
+// newConstructibleType returns a built-in type that creates its values when
+// called, by calling construct.
+func newConstructibleType(name string, construct Callable) *LoxClass {
+	class := newBuiltinType(name)
+	class.construct = construct
+	return class
+}
+
+// typeOf returns the class of an instance, or the built-in type of any
+// other value.
+func typeOf(value interface{}) *LoxClass {
+	switch v := value.(type) {
+	case nil:
+		return nilType
+	case float64:
+		return numberType
+	case string:
+		return stringType
+	case bool:
+		return booleanType
+	case *LoxInstance:
+		return v.Class
+	case *LoxList:
+		return listType
+	case *LoxTuple:
+		return tupleType
+	case *LoxSet:
+		return setType
+	case *LoxClass:
+		return classType
+	case *LoxFiber:
+		return fiberType
+	case *LoxTask:
+		return taskType
+	case *LoxChannel:
+		return channelType
+	case *LoxMutex:
+		return mutexType
+	case *LoxPromise:
+		return promiseType
+	case Callable:
+		return functionType
+	}
+	return nil
+}
+
+func classArgument(value interface{}, function string) (*LoxClass, error) {
+	class, ok := value.(*LoxClass)
+	if !ok {
+		return nil, fmt.Errorf("Argument to %s must be a class.", function)
+	}
+	return class, nil
+}
+
+func instanceArgument(value interface{}, function string) (*LoxInstance, error) {
+	instance, ok := value.(*LoxInstance)
+	if !ok {
+		return nil, fmt.Errorf("Argument to %s must be an instance.", function)
+	}
+	return instance, nil
+}
+
+func attributeName(value interface{}) (string, error) {
+	name, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("Attribute name must be a string.")
+	}
+	return name, nil
+}
+
+// sortedNames returns names as a Lox list of strings in a stable order.
+func sortedNames(names []string) *LoxList {
+	sort.Strings(names)
+	elements := make([]interface{}, len(names))
+	for n, name := range names {
+		elements[n] = name
+	}
+	return NewLoxList(elements)
+}
+
+// TypeFunction implements type(value).
+type TypeFunction struct{}
+
+func (f *TypeFunction) Arity() int {
+	return 1
+}
+
+func (f *TypeFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	class := typeOf(arguments[0])
+	if class == nil {
+		return nil, fmt.Errorf("Value has no type.")
+	}
+	return class, nil
+}
+
+func (f *TypeFunction) String() string {
+	return "<native fn>"
+}
+
+// IsInstanceFunction implements isInstance(value, class). User classes match
+// their subclasses' instances; built-in types match on type() alone.
+type IsInstanceFunction struct{}
+
+func (f *IsInstanceFunction) Arity() int {
+	return 2
+}
+
+func (f *IsInstanceFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	class, err := classArgument(arguments[1], "isInstance")
+	if err != nil {
+		return nil, err
+	}
+	return typeOf(arguments[0]).isSubclassOf(class), nil
+}
+
+func (f *IsInstanceFunction) String() string {
+	return "<native fn>"
+}
+
+// FieldsFunction implements fields(instance), listing field names.
+type FieldsFunction struct{}
+
+func (f *FieldsFunction) Arity() int {
+	return 1
+}
+
+func (f *FieldsFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	instance, err := instanceArgument(arguments[0], "fields")
+	if err != nil {
+		return nil, err
+	}
+	return sortedNames(instance.fieldNames()), nil
+}
+
+func (f *FieldsFunction) String() string {
+	return "<native fn>"
+}
+
+// MethodsFunction implements methods(class), listing the names of the
+// methods its instances respond to, inherited ones included.
+type MethodsFunction struct{}
+
+func (f *MethodsFunction) Arity() int {
+	return 1
+}
+
+func (f *MethodsFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	class, err := classArgument(arguments[0], "methods")
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for c := class; c != nil; c = c.Superclass {
+		for name := range c.Methods {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return sortedNames(names), nil
+}
+
+func (f *MethodsFunction) String() string {
+	return "<native fn>"
+}
+
+// SuperclassOfFunction implements superclassOf(class), which is nil for a
+// class with no superclass.
+type SuperclassOfFunction struct{}
+
+func (f *SuperclassOfFunction) Arity() int {
+	return 1
+}
+
+func (f *SuperclassOfFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	class, err := classArgument(arguments[0], "superclassOf")
+	if err != nil {
+		return nil, err
+	}
+	if class.Superclass == nil {
+		return nil, nil
+	}
+	return class.Superclass, nil
+}
+
+func (f *SuperclassOfFunction) String() string {
+	return "<native fn>"
+}
+
+// HasAttrFunction implements hasattr(value, name).
+type HasAttrFunction struct{}
+
+func (f *HasAttrFunction) Arity() int {
+	return 2
+}
+
+func (f *HasAttrFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	name, err := attributeName(arguments[1])
+	if err != nil {
+		return nil, err
+	}
+	switch object := arguments[0].(type) {
+	case *LoxInstance:
+		if _, ok := object.field(name); ok || object.Class.findProperty(name) != nil {
+			return true, nil
+		}
+		return object.Class.findMethod(name) != nil, nil
+	case PropertyHolder:
+		_, err := object.Get(interpreter, scanner.Token{Type: scanner.IDENTIFIER, Lexeme: name})
+		return err == nil, nil
+	}
+	return false, nil
+}
+
+func (f *HasAttrFunction) String() string {
+	return "<native fn>"
+}
+
+// GetAttrFunction implements getattr(value, name), the same lookup as
+// `value.name` with the name computed at runtime.
+type GetAttrFunction struct{}
+
+func (f *GetAttrFunction) Arity() int {
+	return 2
+}
+
+func (f *GetAttrFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	name, err := attributeName(arguments[1])
+	if err != nil {
+		return nil, err
+	}
+	object, ok := arguments[0].(PropertyHolder)
+	if !ok {
+		return nil, fmt.Errorf("Only instances have properties.")
+	}
+	value, err := object.Get(interpreter, scanner.Token{Type: scanner.IDENTIFIER, Lexeme: name})
+	if runtimeErr, ok := err.(*RuntimeError); ok && runtimeErr.Token.Line == 0 {
+		// The synthesized token has no line; report at the call instead.
+		return nil, fmt.Errorf("%s", runtimeErr.Message)
+	}
+	return value, err
+}
+
+func (f *GetAttrFunction) String() string {
+	return "<native fn>"
+}
+
+// SetAttrFunction implements setattr(instance, name, value).
+type SetAttrFunction struct{}
+
+func (f *SetAttrFunction) Arity() int {
+	return 3
+}
+
+func (f *SetAttrFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	name, err := attributeName(arguments[1])
+	if err != nil {
+		return nil, err
+	}
+	instance, ok := arguments[0].(*LoxInstance)
+	if !ok {
+		return nil, fmt.Errorf("Only instances have fields.")
+	}
+	err = instance.Set(interpreter, scanner.Token{Type: scanner.IDENTIFIER, Lexeme: name}, arguments[2])
+	if err != nil {
+		return nil, err
+	}
+	return arguments[2], nil
+}
+
+func (f *SetAttrFunction) String() string {
+	return "<native fn>"
+}
+
+// DelAttrFunction implements delattr(instance, name), removing a field.
+type DelAttrFunction struct{}
+
+func (f *DelAttrFunction) Arity() int {
+	return 2
+}
+
+func (f *DelAttrFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	name, err := attributeName(arguments[1])
+	if err != nil {
+		return nil, err
+	}
+	instance, ok := arguments[0].(*LoxInstance)
+	if !ok {
+		return nil, fmt.Errorf("Only instances have fields.")
+	}
+	if !instance.deleteField(name) {
+		return nil, fmt.Errorf("Undefined field '%s'.", name)
+	}
+	return nil, nil
+}
+
+func (f *DelAttrFunction) String() string {
+	return "<native fn>"
+}
@@ -2,6 +2,16 @@ package interpreter
 
 type Return struct {
     Value interface{}
+
+    // TailCall is set instead of Value when a function returns the result
+    // of calling another Lox function. The caller's LoxFunction makes that
+    // call itself once the returning function's frame has unwound.
+    TailCall *TailCall
+}
+
+type TailCall struct {
+    Function  *LoxFunction
+    Arguments []interface{}
 }
 
 func (r *Return) Error() string {
@@ -1,12 +1,100 @@
 package interpreter
 
-import "github.com/chase-compton/LOX_GO/scanner"
+import (
+	"fmt"
+
+	"github.com/chase-compton/LOX_GO/errors"
+	"github.com/chase-compton/LOX_GO/scanner"
+)
 
 type RuntimeError struct {
 	Token   scanner.Token
 	Message string
+	// Code classifies the error for diagnostics; empty means a generic
+	// runtime error.
+	Code string
+	// Help is an optional hint shown beneath the error, such as a
+	// suggested spelling.
+	Help string
+	// Frames are the Lox calls in progress when the error was raised,
+	// outermost first. They are empty for errors in top-level code.
+	Frames []CallFrame
+}
+
+// CallFrame is one Lox function call on the interpreter's call stack.
+type CallFrame struct {
+	Function string
+	// Class names the class the function is a method of, if any.
+	Class string
+	// Line is the line of the call, or 0 when native code made the call.
+	Line int
+}
+
+func (f CallFrame) String() string {
+	if f.Class != "" {
+		return f.Class + "." + f.Function
+	}
+	return f.Function
+}
+
+// Traceback describes where each frame was when the error was raised,
+// outermost first, like Python's "most recent call last" listing. A call
+// made from native code has no line to show, so its caller is left out, and
+// the output from deep recursion is shortened.
+func (e *RuntimeError) Traceback() []string {
+	if len(e.Frames) == 0 {
+		return nil
+	}
+	var entries []string
+	where := "script"
+	for _, frame := range e.Frames {
+		if frame.Line != 0 {
+			entries = append(entries, fmt.Sprintf("line %d, in %s", frame.Line, where))
+		}
+		where = frame.String()
+	}
+	entries = append(entries, fmt.Sprintf("line %d, in %s", e.Token.Line, where))
+
+	const maxRepeats = 3
+	var trace []string
+	for n := 0; n < len(entries); {
+		run := 1
+		for n+run < len(entries) && entries[n+run] == entries[n] {
+			run++
+		}
+		for k := 0; k < run && k < maxRepeats; k++ {
+			trace = append(trace, entries[n])
+		}
+		if run > maxRepeats {
+			trace = append(trace, fmt.Sprintf("[Previous line repeated %d more times]", run-maxRepeats))
+		}
+		n += run
+	}
+
+	// Recursion through several functions doesn't repeat line for line, so
+	// also keep only the ends of a very long trace.
+	const keep = 10
+	if len(trace) > 2*keep+1 {
+		omitted := fmt.Sprintf("[%d more lines]", len(trace)-2*keep)
+		trace = append(append(trace[:keep:keep], omitted), trace[len(trace)-keep:]...)
+	}
+	return trace
 }
 
 func (e *RuntimeError) Error() string {
 	return e.Message
 }
+
+// Diagnostic describes the error for reporting, pointing at its token and
+// listing the calls that led to it.
+func (e *RuntimeError) Diagnostic() errors.Diagnostic {
+	code := e.Code
+	if code == "" {
+		code = errors.CodeRuntime
+	}
+	d := e.Token.Diagnostic(code, e.Message)
+	d.Help = e.Help
+	d.Runtime = true
+	d.Trace = e.Traceback()
+	return d
+}
@@ -0,0 +1,232 @@
+package interpreter
+
+// Concurrency in Lox
+//
+// `spawn f(args)` evaluates f and its arguments on the current task, then runs
+// the call on a new goroutine with a forked Interpreter. A task has its own
+// current environment and call stack; it shares only the global environment,
+// the resolver's locals table, and whatever heap values (instances, lists,
+// sets, closures' captured variables) it can reach. The locals table is
+// locked, since the REPL resolves each new line while earlier tasks run.
+// A Go panic in a task, such as one in a native function, becomes the
+// task's error.
+//
+// Data races are defined as follows. Reading or writing a single variable or
+// instance field is atomic, so concurrent tasks never observe a torn value or
+// crash the interpreter. Nothing larger is atomic: `counter = counter + 1`
+// reads and then writes, and two tasks running it can lose an update. Lists
+// and sets are not synchronized at all, and concurrently mutating one is an
+// error that may abort the program. Guard any shared state that is updated in
+// more than one step with a Mutex, or hand values between tasks over a
+// Channel instead of sharing them.
+//
+// The program ends when the main script finishes; tasks that were never
+// joined are abandoned, and an error raised in a task is only reported if
+// some task joins it.
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/chase-compton/LOX_GO/ast"
+	"github.com/chase-compton/LOX_GO/scanner"
+)
+
+// localsTable is the resolver's record of how many scopes out each local
+// variable reference is, shared by an interpreter and its forks.
+type localsTable struct {
+	mu     sync.RWMutex
+	depths map[ast.Expr]int
+}
+
+func (t *localsTable) get(expr ast.Expr) (int, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	depth, ok := t.depths[expr]
+	return depth, ok
+}
+
+func (t *localsTable) set(expr ast.Expr, depth int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.depths[expr] = depth
+}
+
+// LoxTask is the handle returned by `spawn`.
+type LoxTask struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+func (t *LoxTask) String() string {
+	return "<task>"
+}
+
+func (t *LoxTask) Get(interpreter *Interpreter, name scanner.Token) (interface{}, error) {
+	switch name.Lexeme {
+	case "join":
+		return &NativeMethod{Name: "join", arity: 0, fn: func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+			<-t.done
+			return t.value, t.err
+		}}, nil
+	case "isDone":
+		select {
+		case <-t.done:
+			return true, nil
+		default:
+			return false, nil
+		}
+	}
+	return nil, undefinedProperty(name)
+}
+
+// LoxChannel carries values between tasks. Receiving from a closed, drained
+// channel yields nil.
+type LoxChannel struct {
+	ch     chan interface{}
+	mu     sync.Mutex
+	closed bool
+}
+
+func (c *LoxChannel) String() string {
+	return "<channel>"
+}
+
+func (c *LoxChannel) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func (c *LoxChannel) send(value interface{}) (err error) {
+	if c.isClosed() {
+		return fmt.Errorf("Cannot send on a closed channel.")
+	}
+	// The channel may still be closed while this send is blocked.
+	defer func() {
+		if recover() != nil {
+			err = fmt.Errorf("Cannot send on a closed channel.")
+		}
+	}()
+	c.ch <- value
+	return nil
+}
+
+func (c *LoxChannel) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return fmt.Errorf("Channel is already closed.")
+	}
+	c.closed = true
+	close(c.ch)
+	return nil
+}
+
+func (c *LoxChannel) Get(interpreter *Interpreter, name scanner.Token) (interface{}, error) {
+	switch name.Lexeme {
+	case "send":
+		return &NativeMethod{Name: "send", arity: 1, fn: func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+			return nil, c.send(arguments[0])
+		}}, nil
+	case "receive":
+		return &NativeMethod{Name: "receive", arity: 0, fn: func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+			return <-c.ch, nil
+		}}, nil
+	case "close":
+		return &NativeMethod{Name: "close", arity: 0, fn: func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+			return nil, c.close()
+		}}, nil
+	case "isClosed":
+		return c.isClosed(), nil
+	}
+	return nil, undefinedProperty(name)
+}
+
+// ChannelFunction is `Channel()` for an unbuffered channel or
+// `Channel(capacity)` for a buffered one.
+type ChannelFunction struct{}
+
+func (f *ChannelFunction) Arity() int {
+	return VariadicArity
+}
+
+func (f *ChannelFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	capacity, err := optionalArgument(arguments)
+	if err != nil {
+		return nil, err
+	}
+	if capacity == nil {
+		capacity = 0.0
+	}
+	size, ok := capacity.(float64)
+	if !ok || size < 0 || size != float64(int(size)) {
+		return nil, fmt.Errorf("Channel capacity must be a non-negative integer.")
+	}
+	return &LoxChannel{ch: make(chan interface{}, int(size))}, nil
+}
+
+func (f *ChannelFunction) String() string {
+	return "<native fn>"
+}
+
+// LoxMutex is a lock for state shared between tasks. Unlike sync.Mutex,
+// unlocking a mutex that isn't locked is a runtime error rather than a crash.
+type LoxMutex struct {
+	slot chan struct{}
+}
+
+func (m *LoxMutex) String() string {
+	return "<mutex>"
+}
+
+func (m *LoxMutex) unlock() error {
+	select {
+	case <-m.slot:
+		return nil
+	default:
+		return fmt.Errorf("Cannot unlock a mutex that is not locked.")
+	}
+}
+
+func (m *LoxMutex) Get(interpreter *Interpreter, name scanner.Token) (interface{}, error) {
+	switch name.Lexeme {
+	case "lock":
+		return &NativeMethod{Name: "lock", arity: 0, fn: func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+			m.slot <- struct{}{}
+			return nil, nil
+		}}, nil
+	case "unlock":
+		return &NativeMethod{Name: "unlock", arity: 0, fn: func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+			return nil, m.unlock()
+		}}, nil
+	case "withLock":
+		// withLock(fn) calls fn while holding the lock and returns its result,
+		// releasing the lock even if fn raises an error.
+		return &NativeMethod{Name: "withLock", arity: 1, fn: func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+			function, ok := arguments[0].(Callable)
+			if !ok || function.Arity() != 0 {
+				return nil, fmt.Errorf("Argument to 'withLock' must be a function with no parameters.")
+			}
+			m.slot <- struct{}{}
+			defer m.unlock()
+			return function.Call(interpreter, nil)
+		}}, nil
+	}
+	return nil, undefinedProperty(name)
+}
+
+type MutexFunction struct{}
+
+func (f *MutexFunction) Arity() int {
+	return 0
+}
+
+func (f *MutexFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	return &LoxMutex{slot: make(chan struct{}, 1)}, nil
+}
+
+func (f *MutexFunction) String() string {
+	return "<native fn>"
+}
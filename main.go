@@ -2,24 +2,45 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"io"
 	"os"
 	"strings"
 
+	"github.com/chase-compton/LOX_GO/ast"
 	"github.com/chase-compton/LOX_GO/errors"
 	"github.com/chase-compton/LOX_GO/interpreter"
 	"github.com/chase-compton/LOX_GO/parser"
 	"github.com/chase-compton/LOX_GO/resolver"
 	"github.com/chase-compton/LOX_GO/scanner"
+	"github.com/chase-compton/LOX_GO/typecheck"
+)
+
+var (
+	warnExhaustive = flag.Bool("warn-exhaustive", false, "warn about matches that miss a subclass")
+	warn           = flag.String("warn", "", "comma-separated warning codes to enable, or 'all'")
+	maxCallDepth   = flag.Int("max-call-depth", interpreter.DefaultMaxCallDepth, "maximum depth of nested calls (0 for no limit)")
+	noContracts    = flag.Bool("no-contracts", false, "skip assert statements and requires/ensures clauses")
+	typeCheck      = flag.Bool("typecheck", false, "check type annotations before running")
 )
 
 func main() {
-	if len(os.Args) > 2 {
-		fmt.Println("Usage: lox [script]")
+	flag.Usage = func() {
+		fmt.Println("Usage: lox [options] [script]")
+		fmt.Println("       lox [options] check script")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 2 && args[0] == "check" {
+		checkFile(args[1])
+	} else if len(args) > 1 {
+		flag.Usage()
 		os.Exit(64)
-	} else if len(os.Args) == 2 {
-		runFile(os.Args[1])
+	} else if len(args) == 1 {
+		runFile(args[0])
 	} else {
 		runPrompt()
 	}
@@ -32,20 +53,44 @@ func runFile(path string) {
 		os.Exit(1)
 	}
 	source := string(bytes)
-	interp := interpreter.NewInterpreter()
-	runWithInterpreter(source, interp)
+	diagnostics := errors.NewPrinter(os.Stderr)
+	diagnostics.SetSource(path, source)
+	interp := newInterpreter(diagnostics)
+	runWithInterpreter(source, interp, diagnostics)
+	if !diagnostics.HasErrors() && !diagnostics.HasRuntimeErrors() {
+		// Let pending timers and promise callbacks finish before exiting.
+		interp.RunEventLoop()
+	}
 
-	if errors.HadError {
+	if diagnostics.HasErrors() {
 		os.Exit(65)
 	}
-	if errors.HadRuntimeError {
+	if diagnostics.HasRuntimeErrors() {
 		os.Exit(70)
 	}
 }
 
+// checkFile reports the problems in a script that can be found without
+// running it.
+func checkFile(path string) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	source := string(bytes)
+	diagnostics := errors.NewPrinter(os.Stderr)
+	diagnostics.SetSource(path, source)
+	compile(source, newInterpreter(diagnostics), diagnostics, true)
+	if diagnostics.HasErrors() {
+		os.Exit(65)
+	}
+}
+
 func runPrompt() {
 	reader := bufio.NewReader(os.Stdin)
-	interp := interpreter.NewInterpreter() // Create a single interpreter instance
+	diagnostics := errors.NewPrinter(os.Stderr)
+	interp := newInterpreter(diagnostics) // Create a single interpreter instance
 	for {
 		fmt.Print("> ")
 		line, err := reader.ReadString('\n')
@@ -61,41 +106,94 @@ func runPrompt() {
 		}
 		// Remove the trailing newline character
 		line = strings.TrimRight(line, "\r\n")
-		runWithInterpreter(line, interp) // Use the interpreter instance
-		errors.HadError = false
-		errors.HadRuntimeError = false
+		diagnostics.SetSource("", line)
+		runWithInterpreter(line, interp, diagnostics) // Use the interpreter instance
+		if !diagnostics.HasErrors() && !diagnostics.HasRuntimeErrors() {
+			// Fire the timers and promise callbacks the line scheduled.
+			interp.RunEventLoop()
+		}
+		diagnostics.Reset()
+	}
+}
+
+func newInterpreter(diagnostics errors.Diagnostics) *interpreter.Interpreter {
+	interp := interpreter.NewInterpreter(diagnostics)
+	interp.SetMaxCallDepth(*maxCallDepth)
+	interp.SetContracts(!*noContracts)
+	return interp
+}
+
+// enabledWarnings returns the warning codes turned on by the command line.
+func enabledWarnings() []string {
+	var codes []string
+	if *warnExhaustive {
+		codes = append(codes, errors.CodeNonExhaustiveMatch)
 	}
+	for _, code := range strings.Split(*warn, ",") {
+		switch code = strings.TrimSpace(code); code {
+		case "":
+		case "all":
+			codes = append(codes, errors.WarningCodes...)
+		default:
+			codes = append(codes, code)
+		}
+	}
+	return codes
 }
 
-func runWithInterpreter(source string, interp *interpreter.Interpreter) {
-	errors.HadError = false
-	errors.HadRuntimeError = false
+func runWithInterpreter(source string, interp *interpreter.Interpreter, diagnostics *errors.Printer) {
+	statements, ok := compile(source, interp, diagnostics, false)
+	if !ok {
+		return
+	}
+
+	interpretErr := interp.Interpret(statements)
+	if interpretErr != nil {
+		// Runtime errors are already reported by the interpreter.
+		return
+	}
+}
 
-	scanner := scanner.NewScanner(source)
+// compile scans, parses and resolves source, returning the statements ready
+// to run unless errors were reported. With check set, the resolver also
+// looks for undefined globals and mismatched arities. Type annotations are
+// checked with check set or the -typecheck flag.
+func compile(source string, interp *interpreter.Interpreter, diagnostics *errors.Printer, check bool) ([]ast.Stmt, bool) {
+	scanner := scanner.NewScanner(source, diagnostics)
 	tokens := scanner.ScanTokens()
 
-	if errors.HadError {
+	if diagnostics.HasErrors() {
 		// Scanning errors have occurred; do not proceed to parsing.
-		return
+		return nil, false
 	}
 
-	p := parser.NewParser(tokens)
+	p := parser.NewParser(tokens, diagnostics)
 	statements, _ := p.Parse()
-	if errors.HadError {
+	if diagnostics.HasErrors() {
 		// Parsing errors have occurred; do not proceed to interpretation.
-		return
+		return nil, false
 	}
 
-	res := resolver.NewResolver(interp)
+	res := resolver.NewResolver(interp, diagnostics)
+	res.Suppressions = scanner.Suppressions()
+	for _, code := range enabledWarnings() {
+		res.EnableWarning(code)
+	}
 	_ = res.Resolve(statements)
-	if errors.HadError {
+	if check && !diagnostics.HasErrors() {
+		_ = res.Check()
+	}
+	if diagnostics.HasErrors() {
 		// Resolution errors have occurred; do not proceed to interpretation.
-		return
+		return nil, false
 	}
 
-	interpretErr := interp.Interpret(statements)
-	if interpretErr != nil {
-		// Runtime errors are already reported by the interpreter.
-		return
+	if check || *typeCheck {
+		_ = typecheck.NewChecker(diagnostics).Check(statements)
+	}
+	if diagnostics.HasErrors() {
+		// Type errors have occurred; do not proceed to interpretation.
+		return nil, false
 	}
+	return statements, true
 }
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/chase-compton/LOX_GO/ast"
 	"github.com/chase-compton/LOX_GO/errors"
@@ -9,32 +10,66 @@ import (
 )
 
 type Parser struct {
-	tokens  []scanner.Token
-	current int
+	diagnostics errors.Diagnostics
+	tokens      []scanner.Token
+	current     int
+	// depth counts the braces open before the current token, ignoring
+	// unmatched closing braces. advance keeps it up to date.
+	depth int
+	// errors are the syntax errors found so far. lastError is the token the
+	// most recent one was reported at, used to drop repeats.
+	errors    ParseErrors
+	lastError *scanner.Token
 }
 
-func NewParser(tokens []scanner.Token) *Parser {
+// NewParser returns a parser for tokens that reports syntax errors to
+// diagnostics.
+func NewParser(tokens []scanner.Token, diagnostics errors.Diagnostics) *Parser {
 	return &Parser{
-		tokens:  tokens,
-		current: 0,
+		diagnostics: diagnostics,
+		tokens:      tokens,
+		current:     0,
 	}
 }
 
+// Parse parses the whole program. A syntax error doesn't stop it: the parser
+// skips to the next statement and carries on, so it returns every statement
+// that parsed along with a ParseErrors listing all the errors found.
 func (p *Parser) Parse() ([]ast.Stmt, error) {
 	var statements []ast.Stmt
 	for !p.isAtEnd() {
+		start := p.current
 		stmt, err := p.declaration()
 		if err != nil {
-			return nil, err
+			p.recoverFrom(err, start, 0)
+			continue
 		}
 		if stmt != nil {
 			statements = append(statements, stmt)
 		}
 	}
+	if len(p.errors) > 0 {
+		return statements, p.errors
+	}
 	return statements, nil
 }
 
+// ParseExpression parses tokens holding a single expression, such as the
+// argument of an embedder's Eval.
+func (p *Parser) ParseExpression() (ast.Expr, error) {
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isAtEnd() {
+		p.errorWithCode(p.peek(), errors.CodeSyntax, "Expect end of expression.")
+		return nil, p.errors
+	}
+	return expr, nil
+}
+
 func (p *Parser) printStatement() (ast.Stmt, error) {
+	start := p.previous()
 	value, err := p.expression()
 	if err != nil {
 		return nil, err
@@ -43,10 +78,65 @@ func (p *Parser) printStatement() (ast.Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &ast.PrintStmt{Expression: value}, nil
+	return finish(p, start, &ast.PrintStmt{Expression: value}), nil
+}
+
+func (p *Parser) assertStatement() (ast.Stmt, error) {
+	start := p.previous()
+	assertion, err := p.assertion()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(scanner.SEMICOLON, "Expect ';' after assertion.")
+	if err != nil {
+		return nil, err
+	}
+	return finish(p, start, &ast.AssertStmt{Assertion: assertion}), nil
+}
+
+// assertion parses the condition and optional message that follow 'assert',
+// 'requires' or 'ensures', which has just been consumed.
+func (p *Parser) assertion() (*ast.Assertion, error) {
+	keyword := p.previous()
+	first := p.current
+	condition, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	assertion := &ast.Assertion{
+		Keyword:   keyword,
+		Condition: condition,
+		Source:    p.sourceText(first, p.current),
+	}
+
+	if p.match(scanner.COMMA) {
+		assertion.Message, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return assertion, nil
+}
+
+// sourceText rebuilds the source of tokens[from:to], with a single space
+// wherever the tokens were separated.
+func (p *Parser) sourceText(from, to int) string {
+	var text strings.Builder
+	for n := from; n < to; n++ {
+		token := p.tokens[n]
+		if n > from {
+			previous := p.tokens[n-1]
+			if token.Offset > previous.Offset+previous.Length {
+				text.WriteByte(' ')
+			}
+		}
+		text.WriteString(token.Lexeme)
+	}
+	return text.String()
 }
 
 func (p *Parser) expressionStatement() (ast.Stmt, error) {
+	start := p.peek()
 	expr, err := p.expression()
 	if err != nil {
 		return nil, err
@@ -55,7 +145,7 @@ func (p *Parser) expressionStatement() (ast.Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &ast.ExpressionStmt{Expression: expr}, nil
+	return finish(p, start, &ast.ExpressionStmt{Expression: expr}), nil
 }
 
 func (p *Parser) expression() (ast.Expr, error) {
@@ -63,6 +153,7 @@ func (p *Parser) expression() (ast.Expr, error) {
 }
 
 func (p *Parser) equality() (ast.Expr, error) {
+	start := p.peek()
 	expr, err := p.comparison()
 	if err != nil {
 		return nil, err
@@ -74,17 +165,18 @@ func (p *Parser) equality() (ast.Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		expr = &ast.Binary{
+		expr = finish(p, start, &ast.Binary{
 			Left:     expr,
 			Operator: operator,
 			Right:    right,
-		}
+		})
 	}
 
 	return expr, nil
 }
 
 func (p *Parser) comparison() (ast.Expr, error) {
+	start := p.peek()
 	expr, err := p.term()
 	if err != nil {
 		return nil, err
@@ -96,17 +188,18 @@ func (p *Parser) comparison() (ast.Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		expr = &ast.Binary{
+		expr = finish(p, start, &ast.Binary{
 			Left:     expr,
 			Operator: operator,
 			Right:    right,
-		}
+		})
 	}
 
 	return expr, nil
 }
 
 func (p *Parser) term() (ast.Expr, error) {
+	start := p.peek()
 	expr, err := p.factor()
 	if err != nil {
 		return nil, err
@@ -118,17 +211,18 @@ func (p *Parser) term() (ast.Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		expr = &ast.Binary{
+		expr = finish(p, start, &ast.Binary{
 			Left:     expr,
 			Operator: operator,
 			Right:    right,
-		}
+		})
 	}
 
 	return expr, nil
 }
 
 func (p *Parser) factor() (ast.Expr, error) {
+	start := p.peek()
 	expr, err := p.unary()
 	if err != nil {
 		return nil, err
@@ -140,11 +234,11 @@ func (p *Parser) factor() (ast.Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		expr = &ast.Binary{
+		expr = finish(p, start, &ast.Binary{
 			Left:     expr,
 			Operator: operator,
 			Right:    right,
-		}
+		})
 	}
 
 	return expr, nil
@@ -157,28 +251,51 @@ func (p *Parser) unary() (ast.Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &ast.Unary{
+		return finish(p, operator, &ast.Unary{
 			Operator: operator,
 			Right:    right,
-		}, nil
+		}), nil
+	}
+
+	if p.match(scanner.AWAIT) {
+		keyword := p.previous()
+		value, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return finish(p, keyword, &ast.Await{Keyword: keyword, Value: value}), nil
+	}
+
+	if p.match(scanner.SPAWN) {
+		keyword := p.previous()
+		expr, err := p.call()
+		if err != nil {
+			return nil, err
+		}
+		call, ok := expr.(*ast.Call)
+		if !ok {
+			return nil, p.error(keyword, "Expect function call after 'spawn'.")
+		}
+		return finish(p, keyword, &ast.Spawn{Keyword: keyword, Call: call}), nil
 	}
 
 	return p.call()
 }
 
 func (p *Parser) primary() (ast.Expr, error) {
+	start := p.peek()
 	if p.match(scanner.FALSE) {
-		return &ast.Literal{Value: false}, nil
+		return finish(p, start, &ast.Literal{Value: false}), nil
 	}
 	if p.match(scanner.TRUE) {
-		return &ast.Literal{Value: true}, nil
+		return finish(p, start, &ast.Literal{Value: true}), nil
 	}
 	if p.match(scanner.NIL) {
-		return &ast.Literal{Value: nil}, nil
+		return finish(p, start, &ast.Literal{Value: nil}), nil
 	}
 
 	if p.match(scanner.NUMBER, scanner.STRING) {
-		return &ast.Literal{Value: p.previous().Literal}, nil
+		return finish(p, start, &ast.Literal{Value: p.previous().Literal}), nil
 	}
 
 	if p.match(scanner.SUPER) {
@@ -191,34 +308,90 @@ func (p *Parser) primary() (ast.Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &ast.Super{
+		return finish(p, start, &ast.Super{
 			Keyword: keyword,
 			Method:  method,
-		}, nil
+		}), nil
 	}
 
 	if p.match(scanner.THIS) {
-		return &ast.This{Keyword: p.previous()}, nil
+		return finish(p, start, &ast.This{Keyword: p.previous()}), nil
+	}
+
+	if p.match(scanner.MATCH) {
+		keyword := p.previous()
+		subject, cases, err := p.matchBody(true)
+		if err != nil {
+			return nil, err
+		}
+		return finish(p, start, &ast.Match{
+			Keyword: keyword,
+			Subject: subject,
+			Cases:   cases,
+		}), nil
 	}
 
 	if p.match(scanner.IDENTIFIER) {
-		return &ast.Variable{Name: p.previous()}, nil
+		return finish(p, start, &ast.Variable{Name: p.previous()}), nil
+	}
+
+	if p.match(scanner.LEFT_BRACKET) {
+		bracket := p.previous()
+		var elements []ast.Expr
+		if !p.check(scanner.RIGHT_BRACKET) {
+			for {
+				element, err := p.expression()
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, element)
+				if !p.match(scanner.COMMA) {
+					break
+				}
+			}
+		}
+		_, err := p.consume(scanner.RIGHT_BRACKET, "Expect ']' after list elements.")
+		if err != nil {
+			return nil, err
+		}
+		return finish(p, start, &ast.List{Bracket: bracket, Elements: elements}), nil
 	}
 
 	if p.match(scanner.LEFT_PAREN) {
+		paren := p.previous()
 		expr, err := p.expression()
 		if err != nil {
 			return nil, err
 		}
+
+		// A comma turns the grouping into a tuple; `(a,)` has one element.
+		if p.match(scanner.COMMA) {
+			elements := []ast.Expr{expr}
+			for !p.check(scanner.RIGHT_PAREN) {
+				element, err := p.expression()
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, element)
+				if !p.match(scanner.COMMA) {
+					break
+				}
+			}
+			_, err = p.consume(scanner.RIGHT_PAREN, "Expect ')' after tuple elements.")
+			if err != nil {
+				return nil, err
+			}
+			return finish(p, start, &ast.Tuple{Paren: paren, Elements: elements}), nil
+		}
+
 		_, err = p.consume(scanner.RIGHT_PAREN, "Expect ')' after expression.")
 		if err != nil {
 			return nil, err
 		}
-		return &ast.Grouping{Expression: expr}, nil
+		return finish(p, start, &ast.Grouping{Expression: expr}), nil
 	}
 
-	p.error(p.peek(), "Expect expression.")
-	return nil, nil
+	return nil, p.error(p.peek(), "Expect expression.")
 }
 
 func (p *Parser) match(types ...scanner.TokenType) bool {
@@ -240,6 +413,14 @@ func (p *Parser) check(tokenType scanner.TokenType) bool {
 
 func (p *Parser) advance() scanner.Token {
 	if !p.isAtEnd() {
+		switch p.peek().Type {
+		case scanner.LEFT_BRACE:
+			p.depth++
+		case scanner.RIGHT_BRACE:
+			if p.depth > 0 {
+				p.depth--
+			}
+		}
 		p.current++
 	}
 	return p.previous()
@@ -253,6 +434,14 @@ func (p *Parser) peek() scanner.Token {
 	return p.tokens[p.current]
 }
 
+// peekAt looks offset tokens past the current one without consuming anything.
+func (p *Parser) peekAt(offset int) scanner.Token {
+	if p.current+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.current+offset]
+}
+
 func (p *Parser) previous() scanner.Token {
 	return p.tokens[p.current-1]
 }
@@ -266,54 +455,121 @@ func (p *Parser) consume(tokenType scanner.TokenType, message string) (scanner.T
 }
 
 func (p *Parser) error(token scanner.Token, message string) error {
-	isAtEnd := token.Type == scanner.EOF
-	lexeme := token.Lexeme
-	line := token.Line
-	errors.ReportParseError(line, lexeme, isAtEnd, message)
-	return &ParseError{}
+	return p.errorWithCode(token, errors.CodeSyntax, message)
 }
 
-type ParseError struct{}
+func (p *Parser) errorWithCode(token scanner.Token, code, message string) error {
+	err := &ParseError{Token: token, Message: message}
+	// A construct that fails to parse can trip over the same token more than
+	// once; only the first complaint is useful.
+	if p.lastError != nil && p.lastError.Offset == token.Offset && p.lastError.Type == token.Type {
+		return err
+	}
+	p.lastError = &token
+	p.errors = append(p.errors, err)
+	p.diagnostics.Report(token.Diagnostic(code, message))
+	return err
+}
+
+// ParseError is a syntax error at a token.
+type ParseError struct {
+	Token   scanner.Token
+	Message string
+}
 
 func (e *ParseError) Error() string {
-	return "Parse error"
+	return fmt.Sprintf("[line %d] Error at '%s': %s", e.Token.Line, e.Token.Lexeme, e.Message)
 }
 
-func (p *Parser) synchronize() {
-	p.advance()
+// ParseErrors lists every syntax error in a program, in source order.
+type ParseErrors []*ParseError
 
+func (e ParseErrors) Error() string {
+	messages := make([]string, len(e))
+	for n, err := range e {
+		messages[n] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// recoverFrom gets the parser going again after the statement beginning at
+// token start fails to parse inside braces nested depth deep, 0 being the
+// top level. Errors other than syntax errors are bugs and are passed on.
+func (p *Parser) recoverFrom(err error, start, depth int) {
+	if _, ok := err.(*ParseError); !ok {
+		panic(err)
+	}
+	p.synchronize(depth)
+	if p.current == start && !p.isAtEnd() {
+		// Always move on, or the same token would fail again.
+		p.advance()
+	}
+}
+
+// synchronize discards tokens up to the start of the next statement at the
+// given brace depth. Braces opened while skipping are skipped along with
+// their contents, and inside a block it stops before the closing '}', so a
+// broken statement doesn't take the rest of its block down with it.
+func (p *Parser) synchronize(depth int) {
 	for !p.isAtEnd() {
-		if p.previous().Type == scanner.SEMICOLON {
-			return
+		current := p.depth
+		if current <= depth {
+			switch p.peek().Type {
+			case scanner.RIGHT_BRACE:
+				if depth > 0 {
+					return
+				}
+			case scanner.CLASS, scanner.VAR, scanner.FOR, scanner.IF, scanner.WHILE,
+				scanner.PRINT, scanner.RETURN, scanner.ASSERT, scanner.SELECT,
+				scanner.ASYNC, scanner.AT:
+				return
+			case scanner.FUN, scanner.MATCH:
+				// These also turn up inside expressions, as a match or a
+				// mistaken function literal, where they start nothing.
+				if p.afterStatement() {
+					return
+				}
+			}
 		}
 
-		switch p.peek().Type {
-		case scanner.CLASS, scanner.FUN, scanner.VAR, scanner.FOR,
-			scanner.IF, scanner.WHILE, scanner.PRINT, scanner.RETURN:
+		token := p.advance()
+		if token.Type == scanner.SEMICOLON && current <= depth {
 			return
 		}
+	}
+}
 
-		p.advance()
+// afterStatement reports whether the next token looks like the first of a
+// statement: it follows a ';' or a brace, or starts a line.
+func (p *Parser) afterStatement() bool {
+	if p.current == 0 {
+		return true
+	}
+	switch previous := p.previous(); previous.Type {
+	case scanner.SEMICOLON, scanner.LEFT_BRACE, scanner.RIGHT_BRACE:
+		return true
+	default:
+		return previous.Line < p.peek().Line
 	}
 }
 
 func (p *Parser) declaration() (ast.Stmt, error) {
-	defer func() {
-		if r := recover(); r != nil {
-			if _, ok := r.(*ParseError); ok {
-				p.synchronize()
-			} else {
-				panic(r)
-			}
-		}
-	}()
-
+	if p.check(scanner.AT) {
+		return p.decoratedDeclaration()
+	}
 	if p.match(scanner.CLASS) {
 		return p.classDeclaration()
 	}
 	if p.match(scanner.FUN) {
 		return p.function("function")
 	}
+	if p.match(scanner.ASYNC) {
+		_, err := p.consume(scanner.FUN, "Expect 'fun' after 'async'.")
+		if err != nil {
+			return nil, err
+		}
+		return p.asyncFunction("function")
+	}
 	if p.match(scanner.VAR) {
 		return p.varDeclaration()
 	}
@@ -322,11 +578,21 @@ func (p *Parser) declaration() (ast.Stmt, error) {
 }
 
 func (p *Parser) varDeclaration() (ast.Stmt, error) {
+	start := p.previous()
+	if p.match(scanner.LEFT_BRACKET, scanner.LEFT_BRACE) {
+		return p.destructuringDeclaration(start)
+	}
+
 	name, err := p.consume(scanner.IDENTIFIER, "Expect variable name.")
 	if err != nil {
 		return nil, err
 	}
 
+	varType, err := p.optionalType()
+	if err != nil {
+		return nil, err
+	}
+
 	var initializer ast.Expr
 	if p.match(scanner.EQUAL) {
 		initializer, err = p.expression()
@@ -340,10 +606,62 @@ func (p *Parser) varDeclaration() (ast.Stmt, error) {
 		return nil, err
 	}
 
-	return &ast.VarStmt{Name: name, Initializer: initializer}, nil
+	return finish(p, start, &ast.VarStmt{Name: name, Type: varType, Initializer: initializer}), nil
+}
+
+// destructuringDeclaration parses the rest of `var [a, b, ...rest] = xs;` or
+// `var {name, age} = person;` once the opening bracket or brace is consumed.
+func (p *Parser) destructuringDeclaration(start scanner.Token) (ast.Stmt, error) {
+	open := p.previous()
+	closing, closingMessage := scanner.RIGHT_BRACKET, "Expect ']' after destructuring pattern."
+	if open.Type == scanner.LEFT_BRACE {
+		closing, closingMessage = scanner.RIGHT_BRACE, "Expect '}' after destructuring pattern."
+	}
+
+	stmt := &ast.DestructureVarStmt{Open: open}
+	if !p.check(closing) {
+		for {
+			if open.Type == scanner.LEFT_BRACKET && p.match(scanner.DOT_DOT_DOT) {
+				rest, err := p.consume(scanner.IDENTIFIER, "Expect variable name after '...'.")
+				if err != nil {
+					return nil, err
+				}
+				stmt.Rest = &rest
+				break
+			}
+
+			name, err := p.consume(scanner.IDENTIFIER, "Expect variable name.")
+			if err != nil {
+				return nil, err
+			}
+			stmt.Names = append(stmt.Names, name)
+			if !p.match(scanner.COMMA) {
+				break
+			}
+		}
+	}
+
+	_, err := p.consume(closing, closingMessage)
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(scanner.EQUAL, "Expect '=' after destructuring pattern.")
+	if err != nil {
+		return nil, err
+	}
+	stmt.Initializer, err = p.expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(scanner.SEMICOLON, "Expect ';' after variable declaration.")
+	if err != nil {
+		return nil, err
+	}
+	return finish(p, start, stmt), nil
 }
 
 func (p *Parser) assignment() (ast.Expr, error) {
+	start := p.peek()
 	expr, err := p.logic_or()
 	if err != nil {
 		return nil, err
@@ -357,20 +675,41 @@ func (p *Parser) assignment() (ast.Expr, error) {
 		}
 
 		if getExpr, ok := expr.(*ast.Get); ok {
-			return &ast.Set{
+			return finish(p, start, &ast.Set{
 				Object: getExpr.Object,
 				Name:   getExpr.Name,
 				Value:  value,
-			}, nil
+			}), nil
 		} else if variable, ok := expr.(*ast.Variable); ok {
 			name := variable.Name
-			return &ast.Assign{
+			return finish(p, start, &ast.Assign{
 				Name:  name,
 				Value: value,
-			}, nil
+			}), nil
+		} else if index, ok := expr.(*ast.Index); ok {
+			return finish(p, start, &ast.IndexSet{
+				Object:  index.Object,
+				Bracket: index.Bracket,
+				Index:   index.Index,
+				Value:   value,
+			}), nil
+		} else if list, ok := expr.(*ast.List); ok {
+			targets := make([]*ast.Variable, len(list.Elements))
+			for n, element := range list.Elements {
+				variable, ok := element.(*ast.Variable)
+				if !ok {
+					return nil, p.errorWithCode(equals, errors.CodeInvalidAssignmentTarget, "Invalid assignment target.")
+				}
+				targets[n] = variable
+			}
+			return finish(p, start, &ast.DestructureAssign{
+				Bracket: list.Bracket,
+				Targets: targets,
+				Value:   value,
+			}), nil
 		}
 
-		p.error(equals, "Invalid assignment target.")
+		p.errorWithCode(equals, errors.CodeInvalidAssignmentTarget, "Invalid assignment target.")
 	}
 
 	return expr, nil
@@ -389,27 +728,38 @@ func (p *Parser) statement() (ast.Stmt, error) {
 	if p.match(scanner.PRINT) {
 		return p.printStatement()
 	}
+	if p.match(scanner.ASSERT) {
+		return p.assertStatement()
+	}
 	if p.match(scanner.RETURN) {
 		return p.returnStatement()
 	}
+	if p.match(scanner.MATCH) {
+		return p.matchStatement()
+	}
+	if p.match(scanner.SELECT) {
+		return p.selectStatement()
+	}
 	if p.match(scanner.LEFT_BRACE) {
+		start := p.previous()
 		statements, err := p.block()
 		if err != nil {
 			return nil, err
 		}
-		return &ast.BlockStmt{Statements: statements}, nil
+		return finish(p, start, &ast.BlockStmt{Statements: statements}), nil
 	}
 	return p.expressionStatement()
 }
 
 func (p *Parser) block() ([]ast.Stmt, error) {
 	var statements []ast.Stmt
+	depth := p.depth
 
 	for !p.check(scanner.RIGHT_BRACE) && !p.isAtEnd() {
+		start := p.current
 		stmt, err := p.declaration()
 		if err != nil {
-			// Optionally handle error recovery here
-			p.synchronize()
+			p.recoverFrom(err, start, depth)
 			continue
 		}
 		statements = append(statements, stmt)
@@ -424,6 +774,7 @@ func (p *Parser) block() ([]ast.Stmt, error) {
 }
 
 func (p *Parser) ifStatement() (ast.Stmt, error) {
+	start := p.previous()
 	_, err := p.consume(scanner.LEFT_PAREN, "Expect '(' after 'if'.")
 	if err != nil {
 		return nil, err
@@ -452,14 +803,15 @@ func (p *Parser) ifStatement() (ast.Stmt, error) {
 		}
 	}
 
-	return &ast.IfStmt{
+	return finish(p, start, &ast.IfStmt{
 		Condition:  condition,
 		ThenBranch: thenBranch,
 		ElseBranch: elseBranch,
-	}, nil
+	}), nil
 }
 
 func (p *Parser) logic_or() (ast.Expr, error) {
+	start := p.peek()
 	expr, err := p.logic_and()
 	if err != nil {
 		return nil, err
@@ -471,17 +823,18 @@ func (p *Parser) logic_or() (ast.Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		expr = &ast.Logical{
+		expr = finish(p, start, &ast.Logical{
 			Left:     expr,
 			Operator: operator,
 			Right:    right,
-		}
+		})
 	}
 
 	return expr, nil
 }
 
 func (p *Parser) logic_and() (ast.Expr, error) {
+	start := p.peek()
 	expr, err := p.equality()
 	if err != nil {
 		return nil, err
@@ -493,17 +846,18 @@ func (p *Parser) logic_and() (ast.Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		expr = &ast.Logical{
+		expr = finish(p, start, &ast.Logical{
 			Left:     expr,
 			Operator: operator,
 			Right:    right,
-		}
+		})
 	}
 
 	return expr, nil
 }
 
 func (p *Parser) whileStatement() (ast.Stmt, error) {
+	start := p.previous()
 	_, err := p.consume(scanner.LEFT_PAREN, "Expect '(' after 'while'.")
 	if err != nil {
 		return nil, err
@@ -524,18 +878,23 @@ func (p *Parser) whileStatement() (ast.Stmt, error) {
 		return nil, err
 	}
 
-	return &ast.WhileStmt{
+	return finish(p, start, &ast.WhileStmt{
 		Condition: condition,
 		Body:      body,
-	}, nil
+	}), nil
 }
 
 func (p *Parser) forStatement() (ast.Stmt, error) {
+	start := p.previous()
 	_, err := p.consume(scanner.LEFT_PAREN, "Expect '(' after 'for'.")
 	if err != nil {
 		return nil, err
 	}
 
+	if p.check(scanner.VAR) && p.peekAt(1).Type == scanner.IDENTIFIER && p.peekAt(2).Type == scanner.IN {
+		return p.forInStatement(start)
+	}
+
 	// Initializer
 	var initializer ast.Stmt
 	if p.match(scanner.SEMICOLON) {
@@ -584,40 +943,78 @@ func (p *Parser) forStatement() (ast.Stmt, error) {
 		return nil, err
 	}
 
-	// Desugaring
+	// Desugaring. The nodes this creates span the whole for statement,
+	// apart from the increment's statement, which spans the increment.
 	// Increment
 	if increment != nil {
-		body = &ast.BlockStmt{
+		incrementStmt := &ast.ExpressionStmt{Expression: increment}
+		incrementStmt.SetSpan(increment.SourceSpan())
+		body = finish(p, start, &ast.BlockStmt{
 			Statements: []ast.Stmt{
 				body,
-				&ast.ExpressionStmt{Expression: increment},
+				incrementStmt,
 			},
-		}
+		})
 	}
 
 	// Condition
 	if condition == nil {
-		condition = &ast.Literal{Value: true}
+		condition = finish(p, start, &ast.Literal{Value: true})
 	}
-	body = &ast.WhileStmt{
+	body = finish(p, start, &ast.WhileStmt{
 		Condition: condition,
 		Body:      body,
-	}
+	})
 
 	// Initializer
 	if initializer != nil {
-		body = &ast.BlockStmt{
+		body = finish(p, start, &ast.BlockStmt{
 			Statements: []ast.Stmt{
 				initializer,
 				body,
 			},
-		}
+		})
 	}
 
 	return body, nil
 }
 
+func (p *Parser) forInStatement(start scanner.Token) (ast.Stmt, error) {
+	p.advance()
+	name := p.advance()
+	in := p.advance()
+
+	iterable, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(scanner.RIGHT_PAREN, "Expect ')' after for clauses.")
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+
+	return finish(p, start, &ast.ForInStmt{
+		Name:     name,
+		In:       in,
+		Iterable: iterable,
+		Body:     body,
+	}), nil
+}
+
 func (p *Parser) function(kind string) (*ast.FunctionStmt, error) {
+	// The span starts at 'fun' or 'async', or at a plain method's name.
+	start := p.peek()
+	if previous := p.previous().Type; previous == scanner.FUN || previous == scanner.ASYNC {
+		start = p.previous()
+		if previous == scanner.FUN && p.current >= 2 && p.tokens[p.current-2].Type == scanner.ASYNC {
+			start = p.tokens[p.current-2]
+		}
+	}
+
 	name, err := p.consume(scanner.IDENTIFIER, fmt.Sprintf("Expect %s name.", kind))
 	if err != nil {
 		return nil, err
@@ -629,17 +1026,23 @@ func (p *Parser) function(kind string) (*ast.FunctionStmt, error) {
 	}
 
 	var parameters []scanner.Token
+	var parameterTypes []*ast.TypeAnnotation
 	if !p.check(scanner.RIGHT_PAREN) {
 		for {
 			if len(parameters) >= 255 {
-				p.error(p.peek(), "Cannot have more than 255 parameters.")
+				p.errorWithCode(p.peek(), errors.CodeTooManyArguments, "Cannot have more than 255 parameters.")
 			}
 
 			param, err := p.consume(scanner.IDENTIFIER, "Expect parameter name.")
 			if err != nil {
 				return nil, err
 			}
+			paramType, err := p.optionalType()
+			if err != nil {
+				return nil, err
+			}
 			parameters = append(parameters, param)
+			parameterTypes = append(parameterTypes, paramType)
 
 			if !p.match(scanner.COMMA) {
 				break
@@ -652,6 +1055,24 @@ func (p *Parser) function(kind string) (*ast.FunctionStmt, error) {
 		return nil, err
 	}
 
+	returnType, err := p.optionalType()
+	if err != nil {
+		return nil, err
+	}
+
+	var requires, ensures []*ast.Assertion
+	for p.match(scanner.REQUIRES, scanner.ENSURES) {
+		clause, err := p.assertion()
+		if err != nil {
+			return nil, err
+		}
+		if clause.Keyword.Type == scanner.REQUIRES {
+			requires = append(requires, clause)
+		} else {
+			ensures = append(ensures, clause)
+		}
+	}
+
 	_, err = p.consume(scanner.LEFT_BRACE, fmt.Sprintf("Expect '{' before %s body.", kind))
 	if err != nil {
 		return nil, err
@@ -662,11 +1083,65 @@ func (p *Parser) function(kind string) (*ast.FunctionStmt, error) {
 		return nil, err
 	}
 
-	return &ast.FunctionStmt{
-		Name:   name,
-		Params: parameters,
-		Body:   body,
-	}, nil
+	return finish(p, start, &ast.FunctionStmt{
+		Name:       name,
+		Params:     parameters,
+		ParamTypes: parameterTypes,
+		ReturnType: returnType,
+		Body:       body,
+		Requires:   requires,
+		Ensures:    ensures,
+	}), nil
+}
+
+// optionalType parses the `: type` that may follow a variable or parameter
+// name or a parameter list, returning nil when there is none.
+func (p *Parser) optionalType() (*ast.TypeAnnotation, error) {
+	if !p.match(scanner.COLON) {
+		return nil, nil
+	}
+	return p.typeAnnotation()
+}
+
+// typeAnnotation parses a type: one or more type names joined by '|'. 'nil'
+// is a keyword, so it is accepted alongside identifiers.
+func (p *Parser) typeAnnotation() (*ast.TypeAnnotation, error) {
+	start := p.peek()
+	var alternatives []scanner.Token
+	for {
+		if !p.match(scanner.IDENTIFIER, scanner.NIL) {
+			return nil, p.error(p.peek(), "Expect type name.")
+		}
+		alternatives = append(alternatives, p.previous())
+		if !p.match(scanner.PIPE) {
+			break
+		}
+	}
+	return finish(p, start, &ast.TypeAnnotation{Alternatives: alternatives}), nil
+}
+
+// fieldDeclaration parses `name: type;` in a class body.
+func (p *Parser) fieldDeclaration() (*ast.FieldDecl, error) {
+	name := p.advance()
+	p.advance() // The ':'.
+	fieldType, err := p.typeAnnotation()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(scanner.SEMICOLON, "Expect ';' after field declaration.")
+	if err != nil {
+		return nil, err
+	}
+	return finish(p, name, &ast.FieldDecl{Name: name, Type: fieldType}), nil
+}
+
+func (p *Parser) asyncFunction(kind string) (*ast.FunctionStmt, error) {
+	function, err := p.function(kind)
+	if err != nil {
+		return nil, err
+	}
+	function.IsAsync = true
+	return function, nil
 }
 
 func (p *Parser) returnStatement() (ast.Stmt, error) {
@@ -675,10 +1150,24 @@ func (p *Parser) returnStatement() (ast.Stmt, error) {
 	var err error
 
 	if !p.check(scanner.SEMICOLON) {
+		start := p.peek()
 		value, err = p.expression()
 		if err != nil {
 			return nil, err
 		}
+
+		// `return a, b;` returns the tuple (a, b).
+		if p.check(scanner.COMMA) {
+			elements := []ast.Expr{value}
+			for p.match(scanner.COMMA) {
+				element, err := p.expression()
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, element)
+			}
+			value = finish(p, start, &ast.Tuple{Paren: keyword, Elements: elements})
+		}
 	}
 
 	_, err = p.consume(scanner.SEMICOLON, "Expect ';' after return value.")
@@ -686,18 +1175,18 @@ func (p *Parser) returnStatement() (ast.Stmt, error) {
 		return nil, err
 	}
 
-	return &ast.ReturnStmt{
+	return finish(p, keyword, &ast.ReturnStmt{
 		Keyword: keyword,
 		Value:   value,
-	}, nil
+	}), nil
 }
 
-func (p *Parser) finishCall(callee ast.Expr) (ast.Expr, error) {
+func (p *Parser) finishCall(start scanner.Token, callee ast.Expr) (ast.Expr, error) {
 	var arguments []ast.Expr
 	if !p.check(scanner.RIGHT_PAREN) {
 		for {
 			if len(arguments) >= 255 {
-				p.error(p.peek(), "Cannot have more than 255 arguments.")
+				p.errorWithCode(p.peek(), errors.CodeTooManyArguments, "Cannot have more than 255 arguments.")
 			}
 
 			argument, err := p.expression()
@@ -717,14 +1206,15 @@ func (p *Parser) finishCall(callee ast.Expr) (ast.Expr, error) {
 		return nil, err
 	}
 
-	return &ast.Call{
+	return finish(p, start, &ast.Call{
 		Callee:    callee,
 		Paren:     paren,
 		Arguments: arguments,
-	}, nil
+	}), nil
 }
 
 func (p *Parser) call() (ast.Expr, error) {
+	start := p.peek()
 	expr, err := p.primary()
 	if err != nil {
 		return nil, err
@@ -732,7 +1222,7 @@ func (p *Parser) call() (ast.Expr, error) {
 
 	for {
 		if p.match(scanner.LEFT_PAREN) {
-			expr, err = p.finishCall(expr)
+			expr, err = p.finishCall(start, expr)
 			if err != nil {
 				return nil, err
 			}
@@ -741,10 +1231,25 @@ func (p *Parser) call() (ast.Expr, error) {
 			if err != nil {
 				return nil, err
 			}
-			expr = &ast.Get{
+			expr = finish(p, start, &ast.Get{
 				Object: expr,
 				Name:   name,
+			})
+		} else if p.match(scanner.LEFT_BRACKET) {
+			bracket := p.previous()
+			index, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			_, err = p.consume(scanner.RIGHT_BRACKET, "Expect ']' after index.")
+			if err != nil {
+				return nil, err
 			}
+			expr = finish(p, start, &ast.Index{
+				Object:  expr,
+				Bracket: bracket,
+				Index:   index,
+			})
 		} else {
 			break
 		}
@@ -753,7 +1258,61 @@ func (p *Parser) call() (ast.Expr, error) {
 	return expr, nil
 }
 
+// decoratedDeclaration parses one or more decorators followed by the
+// function or class declaration they apply to.
+func (p *Parser) decoratedDeclaration() (ast.Stmt, error) {
+	decorators, err := p.decorators()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.match(scanner.CLASS):
+		stmt, err := p.classDeclaration()
+		if err != nil {
+			return nil, err
+		}
+		stmt.(*ast.ClassStmt).Decorators = decorators
+		return stmt, nil
+	case p.match(scanner.FUN):
+		function, err := p.function("function")
+		if err != nil {
+			return nil, err
+		}
+		function.Decorators = decorators
+		return function, nil
+	case p.match(scanner.ASYNC):
+		_, err := p.consume(scanner.FUN, "Expect 'fun' after 'async'.")
+		if err != nil {
+			return nil, err
+		}
+		function, err := p.asyncFunction("function")
+		if err != nil {
+			return nil, err
+		}
+		function.Decorators = decorators
+		return function, nil
+	}
+	return nil, p.error(p.peek(), "Expect function or class declaration after decorator.")
+}
+
+// decorators parses any `@expression` lines. A decorator is a call
+// expression, so `@memoize`, `@logging.trace` and `@retry(3)` are all allowed.
+func (p *Parser) decorators() ([]*ast.Decorator, error) {
+	var decorators []*ast.Decorator
+	for p.match(scanner.AT) {
+		at := p.previous()
+		expression, err := p.call()
+		if err != nil {
+			return nil, err
+		}
+		decorators = append(decorators, &ast.Decorator{At: at, Expression: expression})
+	}
+	return decorators, nil
+}
+
 func (p *Parser) classDeclaration() (ast.Stmt, error) {
+	start := p.previous()
 	name, err := p.consume(scanner.IDENTIFIER, "Expect class name.")
 	if err != nil {
 		return nil, err
@@ -765,7 +1324,7 @@ func (p *Parser) classDeclaration() (ast.Stmt, error) {
 		if err != nil {
 			return nil, err
 		}
-		superclass = &ast.Variable{Name: p.previous()}
+		superclass = finish(p, p.previous(), &ast.Variable{Name: p.previous()})
 	}
 
 	_, err = p.consume(scanner.LEFT_BRACE, "Expect '{' before class body.")
@@ -773,12 +1332,31 @@ func (p *Parser) classDeclaration() (ast.Stmt, error) {
 		return nil, err
 	}
 
+	var fields []*ast.FieldDecl
 	var methods []*ast.FunctionStmt
 	for !p.check(scanner.RIGHT_BRACE) && !p.isAtEnd() {
-		method, err := p.function("method")
+		if p.check(scanner.IDENTIFIER) && p.peekAt(1).Type == scanner.COLON {
+			field, err := p.fieldDeclaration()
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, field)
+			continue
+		}
+
+		decorators, err := p.decorators()
+		if err != nil {
+			return nil, err
+		}
+		parseMethod := p.function
+		if p.match(scanner.ASYNC) {
+			parseMethod = p.asyncFunction
+		}
+		method, err := parseMethod("method")
 		if err != nil {
 			return nil, err
 		}
+		method.Decorators = decorators
 		methods = append(methods, method)
 	}
 
@@ -787,9 +1365,290 @@ func (p *Parser) classDeclaration() (ast.Stmt, error) {
 		return nil, err
 	}
 
-	return &ast.ClassStmt{
+	return finish(p, start, &ast.ClassStmt{
 		Name:       name,
 		Superclass: superclass,
+		Fields:     fields,
 		Methods:    methods,
-	}, nil
+	}), nil
+}
+
+func (p *Parser) matchStatement() (ast.Stmt, error) {
+	keyword := p.previous()
+	subject, cases, err := p.matchBody(false)
+	if err != nil {
+		return nil, err
+	}
+	return finish(p, keyword, &ast.MatchStmt{
+		Keyword: keyword,
+		Subject: subject,
+		Cases:   cases,
+	}), nil
+}
+
+// matchBody parses everything after the 'match' keyword. In expression form
+// each case yields an expression terminated by ';', otherwise each case runs a
+// statement.
+func (p *Parser) matchBody(isExpression bool) (ast.Expr, []*ast.MatchCase, error) {
+	_, err := p.consume(scanner.LEFT_PAREN, "Expect '(' after 'match'.")
+	if err != nil {
+		return nil, nil, err
+	}
+	subject, err := p.expression()
+	if err != nil {
+		return nil, nil, err
+	}
+	_, err = p.consume(scanner.RIGHT_PAREN, "Expect ')' after match value.")
+	if err != nil {
+		return nil, nil, err
+	}
+	_, err = p.consume(scanner.LEFT_BRACE, "Expect '{' before match cases.")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var cases []*ast.MatchCase
+	for !p.check(scanner.RIGHT_BRACE) && !p.isAtEnd() {
+		matchCase, err := p.matchCase(isExpression)
+		if err != nil {
+			return nil, nil, err
+		}
+		cases = append(cases, matchCase)
+	}
+
+	_, err = p.consume(scanner.RIGHT_BRACE, "Expect '}' after match cases.")
+	if err != nil {
+		return nil, nil, err
+	}
+	return subject, cases, nil
+}
+
+func (p *Parser) matchCase(isExpression bool) (*ast.MatchCase, error) {
+	keyword, err := p.consume(scanner.CASE, "Expect 'case'.")
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []ast.Pattern
+	for {
+		pattern, err := p.pattern()
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+		if !p.match(scanner.COMMA) {
+			break
+		}
+	}
+
+	if len(patterns) > 1 {
+		for _, pattern := range patterns {
+			if bindsNames(pattern) {
+				return nil, p.error(keyword, "Alternative patterns can't bind names.")
+			}
+		}
+	}
+
+	var guard ast.Expr
+	if p.match(scanner.IF) {
+		guard, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = p.consume(scanner.ARROW, "Expect '=>' after case pattern.")
+	if err != nil {
+		return nil, err
+	}
+
+	matchCase := &ast.MatchCase{
+		Keyword:  keyword,
+		Patterns: patterns,
+		Guard:    guard,
+	}
+	if isExpression {
+		matchCase.Value, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.consume(scanner.SEMICOLON, "Expect ';' after case value.")
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		matchCase.Body, err = p.statement()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return matchCase, nil
+}
+
+func (p *Parser) pattern() (ast.Pattern, error) {
+	if p.match(scanner.NUMBER, scanner.STRING) {
+		return &ast.LiteralPattern{Token: p.previous(), Value: p.previous().Literal}, nil
+	}
+	if p.match(scanner.TRUE) {
+		return &ast.LiteralPattern{Token: p.previous(), Value: true}, nil
+	}
+	if p.match(scanner.FALSE) {
+		return &ast.LiteralPattern{Token: p.previous(), Value: false}, nil
+	}
+	if p.match(scanner.NIL) {
+		return &ast.LiteralPattern{Token: p.previous(), Value: nil}, nil
+	}
+	if p.match(scanner.MINUS) {
+		number, err := p.consume(scanner.NUMBER, "Expect number after '-' in pattern.")
+		if err != nil {
+			return nil, err
+		}
+		return &ast.LiteralPattern{Token: number, Value: -number.Literal.(float64)}, nil
+	}
+
+	if p.match(scanner.IDENTIFIER) {
+		name := p.previous()
+		if p.match(scanner.LEFT_PAREN) {
+			class := &ast.Variable{Name: name}
+			class.SetSpan(ast.NewSpan(name, name))
+			paren := p.previous()
+			var fields []ast.Pattern
+			if !p.check(scanner.RIGHT_PAREN) {
+				for {
+					field, err := p.pattern()
+					if err != nil {
+						return nil, err
+					}
+					fields = append(fields, field)
+					if !p.match(scanner.COMMA) {
+						break
+					}
+				}
+			}
+			_, err := p.consume(scanner.RIGHT_PAREN, "Expect ')' after field patterns.")
+			if err != nil {
+				return nil, err
+			}
+			return &ast.ClassPattern{
+				Class:  class,
+				Paren:  paren,
+				Fields: fields,
+			}, nil
+		}
+		if name.Lexeme == "_" {
+			return &ast.WildcardPattern{Token: name}, nil
+		}
+		return &ast.BindingPattern{Name: name}, nil
+	}
+
+	return nil, p.error(p.peek(), "Expect pattern.")
+}
+
+func bindsNames(pattern ast.Pattern) bool {
+	switch pat := pattern.(type) {
+	case *ast.BindingPattern:
+		return true
+	case *ast.ClassPattern:
+		for _, field := range pat.Fields {
+			if bindsNames(field) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *Parser) selectStatement() (ast.Stmt, error) {
+	stmt := &ast.SelectStmt{Keyword: p.previous()}
+	_, err := p.consume(scanner.LEFT_BRACE, "Expect '{' after 'select'.")
+	if err != nil {
+		return nil, err
+	}
+
+	for !p.check(scanner.RIGHT_BRACE) && !p.isAtEnd() {
+		// 'default' is only special here, so it isn't a reserved word.
+		if p.check(scanner.IDENTIFIER) && p.peek().Lexeme == "default" {
+			defaultToken := p.advance()
+			if stmt.Default != nil {
+				return nil, p.error(defaultToken, "Select can only have one default case.")
+			}
+			_, err = p.consume(scanner.ARROW, "Expect '=>' after 'default'.")
+			if err != nil {
+				return nil, err
+			}
+			stmt.Default, err = p.statement()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		selectCase, err := p.selectCase()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Cases = append(stmt.Cases, selectCase)
+	}
+
+	_, err = p.consume(scanner.RIGHT_BRACE, "Expect '}' after select cases.")
+	if err != nil {
+		return nil, err
+	}
+	return finish(p, stmt.Keyword, stmt), nil
+}
+
+func (p *Parser) selectCase() (*ast.SelectCase, error) {
+	keyword, err := p.consume(scanner.CASE, "Expect 'case' or 'default'.")
+	if err != nil {
+		return nil, err
+	}
+	selectCase := &ast.SelectCase{Keyword: keyword}
+
+	if p.match(scanner.VAR) {
+		name, err := p.consume(scanner.IDENTIFIER, "Expect variable name.")
+		if err != nil {
+			return nil, err
+		}
+		selectCase.Name = &name
+		_, err = p.consume(scanner.EQUAL, "Expect '=' after variable name.")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	call, isCall := expr.(*ast.Call)
+	var method *ast.Get
+	if isCall {
+		method, _ = call.Callee.(*ast.Get)
+	}
+	switch {
+	case method != nil && method.Name.Lexeme == "receive" && len(call.Arguments) == 0:
+		selectCase.Channel = method.Object
+	case method != nil && method.Name.Lexeme == "send" && len(call.Arguments) == 1 && selectCase.Name == nil:
+		selectCase.Channel = method.Object
+		selectCase.Value = call.Arguments[0]
+	default:
+		return nil, p.error(keyword, "Select case must be a channel send or receive.")
+	}
+
+	_, err = p.consume(scanner.ARROW, "Expect '=>' after select case.")
+	if err != nil {
+		return nil, err
+	}
+	selectCase.Body, err = p.statement()
+	if err != nil {
+		return nil, err
+	}
+	return selectCase, nil
+}
+
+// finish sets node's span to run from start through the last token consumed
+// and returns the node.
+func finish[T ast.Node](p *Parser, start scanner.Token, node T) T {
+	node.SetSpan(ast.NewSpan(start, p.previous()))
+	return node
 }
@@ -0,0 +1,277 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"unicode"
+
+	"github.com/chase-compton/LOX_GO/interpreter"
+)
+
+var (
+	errorType       = reflect.TypeOf((*error)(nil)).Elem()
+	callableType    = reflect.TypeOf((*interpreter.Callable)(nil)).Elem()
+	interpreterPath = reflect.TypeOf(interpreter.LoxList{}).PkgPath()
+	packagePath     = reflect.TypeOf(goFunction{}).PkgPath()
+)
+
+// toGo converts a Lox value for passing to Go as type t. It reports false if
+// the value can't be one of those.
+func (e *Engine) toGo(value Value, t reflect.Type) (reflect.Value, bool) {
+	if instance, ok := value.(*interpreter.LoxInstance); ok {
+		// An instance of a Go type's class, or a subclass, stands for its
+		// struct.
+		if object, err := e.object(instance); err == nil {
+			if object.Type().AssignableTo(t) {
+				return object, true
+			}
+			if object.Elem().Type().AssignableTo(t) {
+				return object.Elem(), true
+			}
+		}
+	}
+	if value == nil {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(t), true
+		}
+		return reflect.Value{}, false
+	}
+
+	converted := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		converted.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// Range check as floats first: converting an out of range float to
+		// an integer gives an unspecified value. 2^63 is the first float
+		// past math.MaxInt64.
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) || math.IsInf(n, 0) || n < math.MinInt64 || n >= 1<<63 ||
+			converted.OverflowInt(int64(n)) {
+			return reflect.Value{}, false
+		}
+		converted.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) || math.IsInf(n, 0) || n < 0 || n >= 1<<64 ||
+			converted.OverflowUint(uint64(n)) {
+			return reflect.Value{}, false
+		}
+		converted.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		n, ok := value.(float64)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		converted.SetFloat(n)
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		converted.SetString(s)
+	case reflect.Slice:
+		var elements []interface{}
+		switch sequence := value.(type) {
+		case *interpreter.LoxList:
+			elements = sequence.Elements
+		case *interpreter.LoxTuple:
+			elements = sequence.Elements
+		default:
+			return e.passThrough(value, t)
+		}
+		converted = reflect.MakeSlice(t, len(elements), len(elements))
+		for n, element := range elements {
+			item, ok := e.toGo(element, t.Elem())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			converted.Index(n).Set(item)
+		}
+	default:
+		return e.passThrough(value, t)
+	}
+	return converted, true
+}
+
+// passThrough hands a Lox value to Go as it is, for parameters such as
+// interface{} or *interpreter.LoxInstance.
+func (e *Engine) passThrough(value Value, t reflect.Type) (reflect.Value, bool) {
+	if reflect.TypeOf(value).AssignableTo(t) {
+		return reflect.ValueOf(value), true
+	}
+	return reflect.Value{}, false
+}
+
+// fromGo converts a value returned by Go to a Lox value. Numbers become
+// float64, slices become lists, functions become callable natives and
+// pointers to structs become instances, one per pointer, so Lox can use
+// their fields and methods. Types Lox has no value for, such as maps, are
+// an error.
+func (e *Engine) fromGo(value reflect.Value) (Value, error) {
+	switch value.Kind() {
+	case reflect.Invalid:
+		return nil, nil
+	case reflect.Bool:
+		return value.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(value.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), nil
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Interface:
+		if value.IsNil() {
+			return nil, nil
+		}
+		return e.fromGo(value.Elem())
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil, nil
+		}
+		elements := make([]interface{}, value.Len())
+		for n := range elements {
+			element, err := e.fromGo(value.Index(n))
+			if err != nil {
+				return nil, err
+			}
+			elements[n] = element
+		}
+		return interpreter.NewLoxList(elements), nil
+	case reflect.Func:
+		if value.IsNil() {
+			return nil, nil
+		}
+		return &goFunction{engine: e, name: "fn", fn: value}, nil
+	case reflect.Pointer:
+		if value.IsNil() {
+			return nil, nil
+		}
+		if isLoxValue(value.Type()) {
+			return value.Interface(), nil
+		}
+		if value.Elem().Kind() == reflect.Struct {
+			return e.wrap(value), nil
+		}
+	case reflect.Struct:
+		// A field of a struct Lox holds is used in place, so assigning to
+		// its fields changes the outer struct. Anything else is copied.
+		if value.CanAddr() {
+			return e.wrap(value.Addr()), nil
+		}
+		copied := reflect.New(value.Type())
+		copied.Elem().Set(value)
+		return e.wrap(copied), nil
+	}
+	return nil, fmt.Errorf("Can't use a Go %s in Lox.", value.Type())
+}
+
+// convertible reports whether fromGo can convert values of type t. Values
+// in interfaces are checked as they're converted.
+func convertible(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Interface, reflect.Func, reflect.Struct,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice, reflect.Array:
+		return convertible(t.Elem())
+	case reflect.Pointer:
+		return isLoxValue(t) || t.Elem().Kind() == reflect.Struct
+	}
+	return false
+}
+
+// convertibleResults reports whether fromGo can convert every result of the
+// function type t but a final error.
+func convertibleResults(t reflect.Type) bool {
+	for n := 0; n < t.NumOut(); n++ {
+		if out := t.Out(n); !(out == errorType && n == t.NumOut()-1) && !convertible(out) {
+			return false
+		}
+	}
+	return true
+}
+
+// isLoxValue reports whether t is one of the interpreter's own value types,
+// or one of this package's wrappers, which Lox uses as they are.
+func isLoxValue(t reflect.Type) bool {
+	path := t.Elem().PkgPath()
+	return path == interpreterPath || path == packagePath || t.Implements(callableType)
+}
+
+// describe names the Lox values that can be converted to t, for errors.
+func (e *Engine) describe(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice:
+		return "a list"
+	case reflect.Pointer:
+		t = t.Elem()
+	}
+	e.mu.Lock()
+	class, ok := e.types[t]
+	e.mu.Unlock()
+	if ok {
+		return "a " + class.Name + " instance"
+	}
+	return "a " + t.String()
+}
+
+// typeName names the type of a Lox value, for errors.
+func typeName(value Value) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "a boolean"
+	case float64:
+		return "a number"
+	case string:
+		return "a string"
+	case *interpreter.LoxList:
+		return "a list"
+	case *interpreter.LoxTuple:
+		return "a tuple"
+	case *interpreter.LoxInstance:
+		return "a " + v.Class.Name + " instance"
+	case interpreter.Callable:
+		return "a function"
+	}
+	return "a value"
+}
+
+// loxName turns an exported Go name into the camelCase Lox uses: X becomes
+// x, Distance distance, and URLPath urlPath.
+func loxName(name string) string {
+	runes := []rune(name)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	if upper > 1 && upper < len(runes) {
+		// The last capital starts the next word.
+		upper--
+	}
+	for n := 0; n < upper; n++ {
+		runes[n] = unicode.ToLower(runes[n])
+	}
+	return string(runes)
+}
@@ -0,0 +1,233 @@
+// Package lox embeds the Lox interpreter in Go programs.
+//
+// An Engine runs Lox source and evaluates expressions, returning problems as
+// typed errors instead of printing them:
+//
+//	engine := lox.NewEngine()
+//	if err := engine.Run(`fun square(n) { return n * n; }`); err != nil {
+//		log.Fatal(err)
+//	}
+//	result, err := engine.Call("square", 4.0) // 16.0
+package lox
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+
+	"github.com/chase-compton/LOX_GO/ast"
+	"github.com/chase-compton/LOX_GO/errors"
+	"github.com/chase-compton/LOX_GO/interpreter"
+	"github.com/chase-compton/LOX_GO/parser"
+	"github.com/chase-compton/LOX_GO/resolver"
+	"github.com/chase-compton/LOX_GO/scanner"
+	"github.com/chase-compton/LOX_GO/typecheck"
+)
+
+// Value is a Lox value as Go sees it: nil, a bool, a float64 for every
+// number, a string, or one of the interpreter's types for lists, tuples,
+// functions, classes and instances.
+type Value = interface{}
+
+// Engine runs Lox code. Globals persist from one call to the next, so a
+// function defined by Run can be called by a later Eval or Call. An Engine is
+// not safe for concurrent use.
+type Engine struct {
+	interpreter *interpreter.Interpreter
+	diagnostics *errors.Collector
+	typeCheck   bool
+
+	// mu guards types, the classes for Go struct types, registered or not,
+	// which tasks may add to.
+	mu    sync.Mutex
+	types map[reflect.Type]*interpreter.LoxClass
+}
+
+func NewEngine() *Engine {
+	diagnostics := errors.NewCollector()
+	return &Engine{
+		interpreter: interpreter.NewInterpreter(diagnostics),
+		diagnostics: diagnostics,
+		types:       make(map[reflect.Type]*interpreter.LoxClass),
+	}
+}
+
+// SetOutput sends the output of print statements to w instead of standard
+// output.
+func (e *Engine) SetOutput(w io.Writer) {
+	e.interpreter.SetOutput(w)
+}
+
+// SetTypeCheck turns on checking type annotations before code runs, which
+// is off by default.
+func (e *Engine) SetTypeCheck(enabled bool) {
+	e.typeCheck = enabled
+}
+
+// Run runs a program, then any timers and promise callbacks it left
+// pending. It returns a *SyntaxError, *ResolveError or *TypeError if the
+// program can't run, or a *RuntimeError if it stops with an error.
+func (e *Engine) Run(source string) error {
+	defer e.diagnostics.Reset()
+
+	tokens, err := e.scan(source)
+	if err != nil {
+		return err
+	}
+	statements, _ := parser.NewParser(tokens, e.diagnostics).Parse()
+	if e.diagnostics.HasErrors() {
+		return &SyntaxError{Diagnostics: e.reportedErrors()}
+	}
+	if err := e.check(statements); err != nil {
+		return err
+	}
+
+	if err := e.interpreter.Interpret(statements); err != nil {
+		return e.runtimeError(err)
+	}
+	if err := e.interpreter.RunEventLoop(); err != nil {
+		return e.runtimeError(err)
+	}
+	return nil
+}
+
+// RunFile runs the program in the file at path, as Run does.
+func (e *Engine) RunFile(path string) error {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return e.Run(string(source))
+}
+
+// Eval evaluates a single expression, such as "total * 2", in the global
+// scope and returns its value.
+func (e *Engine) Eval(expression string) (Value, error) {
+	defer e.diagnostics.Reset()
+
+	tokens, err := e.scan(expression)
+	if err != nil {
+		return nil, err
+	}
+	expr, _ := parser.NewParser(tokens, e.diagnostics).ParseExpression()
+	if e.diagnostics.HasErrors() {
+		return nil, &SyntaxError{Diagnostics: e.reportedErrors()}
+	}
+	if err := e.check([]ast.Stmt{&ast.ExpressionStmt{Expression: expr}}); err != nil {
+		return nil, err
+	}
+
+	value, err := e.interpreter.Evaluate(expr)
+	if err != nil {
+		return nil, e.runtimeError(err)
+	}
+	return value, nil
+}
+
+// SetGlobal defines a global variable, replacing any existing one. Go
+// values are converted as RegisterFunc converts results.
+func (e *Engine) SetGlobal(name string, value Value) error {
+	converted, err := e.fromGo(reflect.ValueOf(value))
+	if err != nil {
+		return err
+	}
+	e.interpreter.DefineGlobal(name, converted)
+	return nil
+}
+
+// GetGlobal returns the value of a global variable and whether it is
+// defined.
+func (e *Engine) GetGlobal(name string) (Value, bool) {
+	return e.interpreter.LookupGlobal(name)
+}
+
+// Call calls the global function or class named name with arguments, which
+// are converted as SetGlobal converts values.
+func (e *Engine) Call(name string, arguments ...Value) (Value, error) {
+	callee, ok := e.GetGlobal(name)
+	if !ok {
+		return nil, &RuntimeError{Diagnostic: errors.Diagnostic{
+			Severity: errors.SeverityError,
+			Code:     errors.CodeUndefinedVariable,
+			Message:  fmt.Sprintf("Undefined variable '%s'.", name),
+			Runtime:  true,
+		}}
+	}
+	return e.CallValue(callee, arguments...)
+}
+
+// CallValue calls a Lox function or class, such as one returned by Eval,
+// with arguments, which are converted as SetGlobal converts values.
+func (e *Engine) CallValue(callee Value, arguments ...Value) (Value, error) {
+	defer e.diagnostics.Reset()
+
+	converted := make([]interface{}, len(arguments))
+	for n, argument := range arguments {
+		value, err := e.fromGo(reflect.ValueOf(argument))
+		if err != nil {
+			return nil, err
+		}
+		converted[n] = value
+	}
+	result, err := e.interpreter.CallValue(callee, converted)
+	if err != nil {
+		return nil, e.runtimeError(err)
+	}
+	return result, nil
+}
+
+// Close ends the goroutines of fibers Lox code left suspended. Call it when
+// done with an Engine; code run afterwards can't resume those fibers.
+func (e *Engine) Close() {
+	e.interpreter.Close()
+}
+
+func (e *Engine) scan(source string) ([]scanner.Token, error) {
+	tokens := scanner.NewScanner(source, e.diagnostics).ScanTokens()
+	if e.diagnostics.HasErrors() {
+		return nil, &SyntaxError{Diagnostics: e.reportedErrors()}
+	}
+	return tokens, nil
+}
+
+// check resolves parsed statements, and type checks them if type checking
+// is on.
+func (e *Engine) check(statements []ast.Stmt) error {
+	_ = resolver.NewResolver(e.interpreter, e.diagnostics).Resolve(statements)
+	if e.diagnostics.HasErrors() {
+		return &ResolveError{Diagnostics: e.reportedErrors()}
+	}
+	if !e.typeCheck {
+		return nil
+	}
+	_ = typecheck.NewChecker(e.diagnostics).Check(statements)
+	if e.diagnostics.HasErrors() {
+		return &TypeError{Diagnostics: e.reportedErrors()}
+	}
+	return nil
+}
+
+// errors returns the errors reported so far, leaving out warnings.
+func (e *Engine) reportedErrors() []errors.Diagnostic {
+	var found []errors.Diagnostic
+	for _, d := range e.diagnostics.Diagnostics() {
+		if d.Severity == errors.SeverityError {
+			found = append(found, d)
+		}
+	}
+	return found
+}
+
+// runtimeError wraps err, which the interpreter has just reported, with the
+// diagnostic it reported.
+func (e *Engine) runtimeError(err error) error {
+	reported := e.diagnostics.Diagnostics()
+	for n := len(reported) - 1; n >= 0; n-- {
+		if reported[n].Runtime {
+			return &RuntimeError{Diagnostic: reported[n], err: err}
+		}
+	}
+	return &RuntimeError{Diagnostic: errors.InternalError(err.Error()), err: err}
+}
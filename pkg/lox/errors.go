@@ -0,0 +1,66 @@
+package lox
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chase-compton/LOX_GO/errors"
+)
+
+// SyntaxError is returned for source that can't be scanned or parsed.
+type SyntaxError struct {
+	Diagnostics []errors.Diagnostic
+}
+
+func (e *SyntaxError) Error() string {
+	return describe(e.Diagnostics)
+}
+
+// ResolveError is returned for a program that parses but misuses names or
+// constructs, such as reading a local in its own initializer or returning
+// from top-level code.
+type ResolveError struct {
+	Diagnostics []errors.Diagnostic
+}
+
+func (e *ResolveError) Error() string {
+	return describe(e.Diagnostics)
+}
+
+// TypeError is returned for a program whose type annotations don't hold.
+type TypeError struct {
+	Diagnostics []errors.Diagnostic
+}
+
+func (e *TypeError) Error() string {
+	return describe(e.Diagnostics)
+}
+
+// RuntimeError is returned when Lox code stops with an error. It wraps the
+// interpreter's *interpreter.RuntimeError, if there was one.
+type RuntimeError struct {
+	Diagnostic errors.Diagnostic
+	err        error
+}
+
+func (e *RuntimeError) Error() string {
+	return describe([]errors.Diagnostic{e.Diagnostic})
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.err
+}
+
+// describe lists diagnostics one per line, each with the line it was found
+// on when it has one.
+func describe(diagnostics []errors.Diagnostic) string {
+	lines := make([]string, len(diagnostics))
+	for n, d := range diagnostics {
+		if d.Line > 0 {
+			lines[n] = fmt.Sprintf("[line %d] Error: %s", d.Line, d.Message)
+		} else {
+			lines[n] = fmt.Sprintf("Error: %s", d.Message)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
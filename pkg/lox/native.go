@@ -0,0 +1,273 @@
+package lox
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/chase-compton/LOX_GO/interpreter"
+)
+
+// RegisterFunc defines a global Lox function that calls fn, which must be a
+// Go function. Arguments are converted to fn's parameter types: numbers to
+// any numeric type, integers only when they're whole, lists to slices, and
+// instances of registered types to their structs. Any Lox value can be
+// passed as interface{}. Results are converted back; two or more become a
+// tuple. A non-nil error as the last result becomes a runtime error at the
+// call. Results of types Lox has no value for, such as maps and channels,
+// are rejected.
+func (e *Engine) RegisterFunc(name string, fn interface{}) error {
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func {
+		return fmt.Errorf("lox: RegisterFunc(%q) needs a function, not %T", name, fn)
+	}
+	t := value.Type()
+	for n := 0; n < t.NumOut(); n++ {
+		switch out := t.Out(n); {
+		case out == errorType && n < t.NumOut()-1:
+			return fmt.Errorf("lox: RegisterFunc(%q): only the last result may be an error", name)
+		case out != errorType && !convertible(out):
+			return fmt.Errorf("lox: RegisterFunc(%q): can't convert result type %s to a Lox value", name, out)
+		}
+	}
+	return e.SetGlobal(name, &goFunction{engine: e, name: name, fn: value})
+}
+
+// RegisterType defines a global Lox class for the struct type of prototype,
+// which may be a struct or a pointer to one. The class's instances expose
+// the struct's exported fields and methods, renamed to start in lower case:
+// a field Name is read as `p.name` and a method Area called as `p.area()`.
+// A `lox:"name"` tag on a field renames it, and `lox:"-"` hides it, as are
+// fields and methods using types Lox has no value for.
+//
+// Calling the class creates a zero struct and passes the arguments to its
+// Init method if it has one, or else assigns them to the exported fields in
+// order. The class is an ordinary Lox class, so Lox code can subclass it;
+// a subclass with its own init must call super.init() to create the
+// struct.
+//
+// A struct pointer Go returns to Lox becomes a new instance of the struct
+// type's class each time, even if a Lox subclass created the struct, but
+// Lox treats every instance for the same pointer as the same object.
+func (e *Engine) RegisterType(name string, prototype interface{}) error {
+	t := reflect.TypeOf(prototype)
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("lox: RegisterType(%q) needs a struct, not %T", name, prototype)
+	}
+	class := e.classFor(t)
+	class.Name = name
+	return e.SetGlobal(name, class)
+}
+
+// classFor returns the class for a struct type, creating it the first time
+// the type is registered or returned to Lox.
+func (e *Engine) classFor(t reflect.Type) *interpreter.LoxClass {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if class, ok := e.types[t]; ok {
+		return class
+	}
+
+	var properties []*interpreter.NativeProperty
+	var fieldOrder []int
+	for n := 0; n < t.NumField(); n++ {
+		field := t.Field(n)
+		if !field.IsExported() || field.Anonymous || !convertible(field.Type) {
+			continue
+		}
+		name := loxName(field.Name)
+		if tag, ok := field.Tag.Lookup("lox"); ok {
+			if tag == "-" {
+				continue
+			}
+			name = tag
+		}
+		properties = append(properties, e.field(name, n))
+		fieldOrder = append(fieldOrder, n)
+	}
+
+	// Without an Init method, the arguments are assigned to the fields.
+	init := e.initializer(t, len(fieldOrder), func(_ *interpreter.Interpreter, object reflect.Value, arguments []interface{}) error {
+		for n, argument := range arguments {
+			field := t.Field(fieldOrder[n])
+			if err := e.setField(object.Elem().Field(fieldOrder[n]), loxName(field.Name), argument); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	methods := []*interpreter.NativeFunction{init}
+	pointer := reflect.PointerTo(t)
+	for n := 0; n < pointer.NumMethod(); n++ {
+		method := pointer.Method(n)
+		if !convertibleResults(method.Type) {
+			continue
+		}
+		if method.Name == "Init" {
+			methods[0] = e.initializer(t, arity(method.Type, 1), func(interp *interpreter.Interpreter, object reflect.Value, arguments []interface{}) error {
+				_, err := e.call(interp, "init", object.Method(method.Index), arguments)
+				return err
+			})
+			continue
+		}
+		methods = append(methods, e.method(loxName(method.Name), method))
+	}
+
+	class := interpreter.NewNativeClass(t.Name(), nil, methods...)
+	for _, property := range properties {
+		class.DefineProperty(property)
+	}
+	e.types[t] = class
+	return class
+}
+
+// initializer returns the init method of the class for struct type t. It
+// creates the struct, keeps it on the instance, and then runs set up.
+func (e *Engine) initializer(t reflect.Type, arity int, setUp func(*interpreter.Interpreter, reflect.Value, []interface{}) error) *interpreter.NativeFunction {
+	return interpreter.NewNativeFunction("init", arity, func(interp *interpreter.Interpreter, this *interpreter.LoxInstance, arguments []interface{}) (interface{}, error) {
+		object := reflect.New(t)
+		this.SetState(object.Interface())
+		return nil, setUp(interp, object, arguments)
+	})
+}
+
+// method returns a native method calling the Go method on the instance's
+// struct.
+func (e *Engine) method(name string, method reflect.Method) *interpreter.NativeFunction {
+	return interpreter.NewNativeFunction(name, arity(method.Type, 1), func(interp *interpreter.Interpreter, this *interpreter.LoxInstance, arguments []interface{}) (interface{}, error) {
+		object, err := e.object(this)
+		if err != nil {
+			return nil, err
+		}
+		return e.call(interp, name, object.Method(method.Index), arguments)
+	})
+}
+
+// field returns a native property for the struct field at index.
+func (e *Engine) field(name string, index int) *interpreter.NativeProperty {
+	return &interpreter.NativeProperty{
+		Name: name,
+		Get: func(this *interpreter.LoxInstance) (interface{}, error) {
+			object, err := e.object(this)
+			if err != nil {
+				return nil, err
+			}
+			return e.fromGo(object.Elem().Field(index))
+		},
+		Set: func(this *interpreter.LoxInstance, value interface{}) error {
+			object, err := e.object(this)
+			if err != nil {
+				return err
+			}
+			return e.setField(object.Elem().Field(index), name, value)
+		},
+	}
+}
+
+func (e *Engine) setField(field reflect.Value, name string, value interface{}) error {
+	converted, ok := e.toGo(value, field.Type())
+	if !ok {
+		return fmt.Errorf("Expected %s for field '%s' but got %s.", e.describe(field.Type()), name, typeName(value))
+	}
+	field.Set(converted)
+	return nil
+}
+
+// object returns the pointer to the struct behind an instance.
+func (e *Engine) object(this *interpreter.LoxInstance) (reflect.Value, error) {
+	object := reflect.ValueOf(this.State())
+	if object.Kind() != reflect.Pointer || object.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("%s instance has no Go value; its init must call super.init().", this.Class.Name)
+	}
+	return object, nil
+}
+
+// wrap returns a Lox instance for pointer, a pointer to a struct. Nothing
+// remembers it, so the Go value can be collected once neither side uses
+// it. Each instance for the same pointer is a new one of the struct type's
+// class, without fields Lox code set on another, but Lox sees them all as
+// the same object.
+func (e *Engine) wrap(pointer reflect.Value) *interpreter.LoxInstance {
+	instance := interpreter.NewLoxInstance(e.classFor(pointer.Type().Elem()))
+	instance.SetState(pointer.Interface())
+	return instance
+}
+
+// call calls fn, a Go function, with Lox arguments, converting them and
+// its results.
+func (e *Engine) call(interp *interpreter.Interpreter, name string, fn reflect.Value, arguments []interface{}) (interface{}, error) {
+	t := fn.Type()
+	if t.IsVariadic() && len(arguments) < t.NumIn()-1 {
+		return nil, fmt.Errorf("Expected at least %d arguments but got %d.", t.NumIn()-1, len(arguments))
+	}
+
+	in := make([]reflect.Value, len(arguments))
+	for n, argument := range arguments {
+		var parameter reflect.Type
+		if t.IsVariadic() && n >= t.NumIn()-1 {
+			parameter = t.In(t.NumIn() - 1).Elem()
+		} else {
+			parameter = t.In(n)
+		}
+		value, ok := e.toGo(argument, parameter)
+		if !ok {
+			return nil, fmt.Errorf("Expected %s for argument %d of '%s' but got %s.",
+				e.describe(parameter), n+1, name, typeName(argument))
+		}
+		in[n] = value
+	}
+
+	out := fn.Call(in)
+	if len(out) > 0 && t.Out(len(out)-1) == errorType {
+		if err := out[len(out)-1]; !err.IsNil() {
+			return nil, err.Interface().(error)
+		}
+		out = out[:len(out)-1]
+	}
+
+	switch len(out) {
+	case 0:
+		return nil, nil
+	case 1:
+		return e.fromGo(out[0])
+	}
+	results := make([]interface{}, len(out))
+	for n, result := range out {
+		value, err := e.fromGo(result)
+		if err != nil {
+			return nil, err
+		}
+		results[n] = value
+	}
+	return interpreter.NewLoxTuple(results), nil
+}
+
+// arity returns the Lox arity of a function type, leaving out its first
+// skip parameters, such as a method's receiver.
+func arity(t reflect.Type, skip int) int {
+	if t.IsVariadic() {
+		return interpreter.VariadicArity
+	}
+	return t.NumIn() - skip
+}
+
+// goFunction is a Go function called from Lox.
+type goFunction struct {
+	engine *Engine
+	name   string
+	fn     reflect.Value
+}
+
+func (f *goFunction) Arity() int {
+	return arity(f.fn.Type(), 0)
+}
+
+func (f *goFunction) Call(interp *interpreter.Interpreter, arguments []interface{}) (interface{}, error) {
+	return f.engine.call(interp, f.name, f.fn, arguments)
+}
+
+func (f *goFunction) String() string {
+	return fmt.Sprintf("<native fn %s>", f.name)
+}
@@ -0,0 +1,121 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/chase-compton/LOX_GO/ast"
+	"github.com/chase-compton/LOX_GO/errors"
+	"github.com/chase-compton/LOX_GO/scanner"
+)
+
+// problem is a mistake Check found, reported once they are all in order.
+type problem struct {
+	token      scanner.Token
+	diagnostic errors.Diagnostic
+}
+
+// callSite is a call whose callee names a global.
+type callSite struct {
+	name      scanner.Token
+	paren     scanner.Token
+	arguments int
+}
+
+// Check looks over a resolved program for mistakes that would otherwise
+// only show up if the code containing them ran: references to globals that
+// are declared nowhere, and calls to top-level functions and classes with
+// the wrong number of arguments. It must follow Resolve, and returns a
+// ResolveErrors listing what it found, in the order they appear in the
+// source.
+func (r *Resolver) Check() error {
+	defined := make(map[string]bool)
+	names := r.interpreter.GlobalNames()
+	for _, name := range names {
+		defined[name] = true
+	}
+	for name := range r.globals {
+		defined[name] = true
+		names = append(names, name)
+	}
+
+	var found []problem
+	for _, name := range r.globalUses {
+		if defined[name.Lexeme] {
+			continue
+		}
+		d := name.Diagnostic(errors.CodeUndefinedVariable, fmt.Sprintf("Undefined variable '%s'.", name.Lexeme))
+		d.Help = errors.DidYouMean(name.Lexeme, names)
+		found = append(found, problem{token: name, diagnostic: d})
+	}
+
+	for _, call := range r.calls {
+		arity, ok := r.arity(call.name.Lexeme, make(map[string]bool))
+		if ok && arity != call.arguments {
+			message := fmt.Sprintf("Expected %d arguments but got %d.", arity, call.arguments)
+			found = append(found, problem{token: call.paren, diagnostic: call.paren.Diagnostic(errors.CodeArityMismatch, message)})
+		}
+	}
+
+	sort.SliceStable(found, func(a, b int) bool {
+		return found[a].token.Offset < found[b].token.Offset
+	})
+	for _, p := range found {
+		r.errors = append(r.errors, &ResolveError{Token: p.token, Message: p.diagnostic.Message})
+		r.diagnostics.Report(p.diagnostic)
+	}
+
+	if len(r.errors) > 0 {
+		return r.errors
+	}
+	return nil
+}
+
+// arity returns how many arguments a call to the global name takes, if that
+// can be known before running: name must be declared once, never assigned,
+// and be an undecorated function or class.
+func (r *Resolver) arity(name string, seen map[string]bool) (int, bool) {
+	declarations := r.declarations[name]
+	if len(declarations) != 1 || r.reassigned[name] || seen[name] {
+		return 0, false
+	}
+	seen[name] = true
+
+	switch declaration := declarations[0].(type) {
+	case *ast.FunctionStmt:
+		if len(declaration.Decorators) > 0 {
+			return 0, false
+		}
+		return len(declaration.Params), true
+	case *ast.ClassStmt:
+		return r.classArity(declaration, seen)
+	}
+	return 0, false
+}
+
+// classArity returns the arity of a class's initializer, which may be
+// inherited.
+func (r *Resolver) classArity(class *ast.ClassStmt, seen map[string]bool) (int, bool) {
+	if len(class.Decorators) > 0 {
+		return 0, false
+	}
+	for _, method := range class.Methods {
+		if method.Name.Lexeme == "init" {
+			if len(method.Decorators) > 0 {
+				return 0, false
+			}
+			return len(method.Params), true
+		}
+	}
+	if class.Superclass == nil {
+		return 0, true
+	}
+	superclass := class.Superclass.Name.Lexeme
+	if len(r.declarations[superclass]) != 1 {
+		return 0, false
+	}
+	if _, ok := r.declarations[superclass][0].(*ast.ClassStmt); !ok {
+		return 0, false
+	}
+	return r.arity(superclass, seen)
+}
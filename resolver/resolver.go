@@ -2,6 +2,7 @@ package resolver
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/chase-compton/LOX_GO/ast"
 	"github.com/chase-compton/LOX_GO/errors"
@@ -10,10 +11,43 @@ import (
 )
 
 type Resolver struct {
+	diagnostics     errors.Diagnostics
 	interpreter     *interpreter.Interpreter
 	scopes          []map[string]bool
 	currentClass    ClassType
 	currentFunction FunctionType
+	inAsync         bool
+	// inEnsures is set in a function with ensures clauses, whose returned
+	// calls can't be tail calls: the clauses must see their results.
+	inEnsures bool
+	errors    ResolveErrors
+
+	// Suppressions are the `// lox:ignore` comments found by the scanner.
+	Suppressions errors.Suppressions
+	warnings     map[string]bool
+	pending      []errors.Diagnostic
+	// locals parallels scopes, tracking the declared variables for the
+	// warnings in warnings.go.
+	locals  []map[string]*local
+	globals map[string]bool
+	// declarations, globalUses, reassigned and calls gather what Check needs
+	// to know about globals.
+	declarations  map[string][]ast.Stmt
+	globalUses    []scanner.Token
+	reassigned    map[string]bool
+	calls         []callSite
+	functionDepth int
+	loopDepth     int
+	subclasses    map[string][]string
+	superclasses  map[string]string
+	matches       []matchSite
+}
+
+// matchSite remembers a match so its exhaustiveness can be checked once every
+// class declaration has been seen.
+type matchSite struct {
+	keyword scanner.Token
+	cases   []*ast.MatchCase
 }
 
 type ClassType int
@@ -68,19 +102,64 @@ func (r *Resolver) VisitExpressionStmt(stmt *ast.ExpressionStmt) (interface{}, e
 	return r.resolveExpr(stmt.Expression)
 }
 
-func NewResolver(interpreter *interpreter.Interpreter) *Resolver {
+// NewResolver returns a resolver that records variable bindings in
+// interpreter and reports problems to diagnostics.
+func NewResolver(interpreter *interpreter.Interpreter, diagnostics errors.Diagnostics) *Resolver {
 	return &Resolver{
-		interpreter: interpreter,
-		scopes:      make([]map[string]bool, 0),
+		diagnostics:  diagnostics,
+		interpreter:  interpreter,
+		scopes:       make([]map[string]bool, 0),
+		warnings:     make(map[string]bool),
+		globals:      make(map[string]bool),
+		declarations: make(map[string][]ast.Stmt),
+		reassigned:   make(map[string]bool),
+		subclasses:   make(map[string][]string),
+		superclasses: make(map[string]string),
 	}
 }
 
+// Resolve resolves the whole program. Like the parser it carries on past
+// errors, returning a ResolveErrors listing all of them.
 func (r *Resolver) Resolve(statements []ast.Stmt) error {
+	r.collectGlobals(statements)
 	err := r.resolveStatements(statements)
 	if err != nil {
-		errors.ReportResolverError(err.Error())
+		return err
 	}
-	return err
+	if len(r.errors) > 0 {
+		return r.errors
+	}
+	r.checkExhaustiveness()
+	r.reportWarnings()
+	return nil
+}
+
+// ResolveError is a problem the resolver found at a token.
+type ResolveError struct {
+	Token   scanner.Token
+	Message string
+}
+
+func (e *ResolveError) Error() string {
+	return fmt.Sprintf("[line %d] Error at '%s': %s", e.Token.Line, e.Token.Lexeme, e.Message)
+}
+
+// ResolveErrors lists every problem the resolver found, in source order.
+type ResolveErrors []*ResolveError
+
+func (e ResolveErrors) Error() string {
+	messages := make([]string, len(e))
+	for n, err := range e {
+		messages[n] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// error reports a problem at token and lets resolution carry on, so that one
+// run finds every problem in the program.
+func (r *Resolver) error(token scanner.Token, code, message string) {
+	r.errors = append(r.errors, &ResolveError{Token: token, Message: message})
+	r.diagnostics.Report(token.Diagnostic(code, message))
 }
 
 func (r *Resolver) VisitMethodStmt(stmt *ast.FunctionStmt) (interface{}, error) {
@@ -97,16 +176,20 @@ func (r *Resolver) VisitMethodStmt(stmt *ast.FunctionStmt) (interface{}, error)
 
 func (r *Resolver) VisitReturnStmt(stmt *ast.ReturnStmt) (interface{}, error) {
 	if r.currentFunction == FunctionTypeNone {
-		return nil, fmt.Errorf("Can't return from top-level code.")
+		r.error(stmt.Keyword, errors.CodeInvalidPlacement, "Can't return from top-level code.")
 	}
 	if r.currentFunction == FunctionTypeInitializer && stmt.Value != nil {
-		return nil, fmt.Errorf("Can't return a value from an initializer.")
+		r.error(stmt.Keyword, errors.CodeInvalidPlacement, "Can't return a value from an initializer.")
 	}
 	if stmt.Value != nil {
 		_, err := r.resolveExpr(stmt.Value)
 		if err != nil {
 			return nil, err
 		}
+		// Nothing runs in this function after a returned call, so the
+		// interpreter can reuse the caller's Go frame for it.
+		_, stmt.TailCall = stmt.Value.(*ast.Call)
+		stmt.TailCall = stmt.TailCall && !r.inEnsures
 	}
 	return nil, nil
 }
@@ -114,18 +197,12 @@ func (r *Resolver) VisitReturnStmt(stmt *ast.ReturnStmt) (interface{}, error) {
 func (r *Resolver) VisitBlockStmt(stmt *ast.BlockStmt) (interface{}, error) {
 	r.beginScope()
 	err := r.resolveStatements(stmt.Statements)
-	if err != nil {
-		return nil, err
-	}
 	r.endScope()
-	return nil, nil
+	return nil, err
 }
 
 func (r *Resolver) VisitVarStmt(stmt *ast.VarStmt) (interface{}, error) {
-	err := r.declare(stmt.Name)
-	if err != nil {
-		return nil, err
-	}
+	r.declare(stmt.Name)
 	if stmt.Initializer != nil {
 		_, err := r.resolveExpr(stmt.Initializer)
 		if err != nil {
@@ -137,11 +214,13 @@ func (r *Resolver) VisitVarStmt(stmt *ast.VarStmt) (interface{}, error) {
 }
 
 func (r *Resolver) VisitFunctionStmt(stmt *ast.FunctionStmt) (interface{}, error) {
-	err := r.declare(stmt.Name)
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+
+	err := r.resolveDecorators(stmt.Decorators)
 	if err != nil {
 		return nil, err
 	}
-	r.define(stmt.Name)
 
 	err = r.resolveFunction(stmt, FunctionTypeFunction)
 	if err != nil {
@@ -151,6 +230,7 @@ func (r *Resolver) VisitFunctionStmt(stmt *ast.FunctionStmt) (interface{}, error
 }
 
 func (r *Resolver) VisitIfStmt(stmt *ast.IfStmt) (interface{}, error) {
+	r.checkCondition(stmt.Condition, false)
 	_, err := r.resolveExpr(stmt.Condition)
 	if err != nil {
 		return nil, err
@@ -172,7 +252,26 @@ func (r *Resolver) VisitPrintStmt(stmt *ast.PrintStmt) (interface{}, error) {
 	return r.resolveExpr(stmt.Expression)
 }
 
+func (r *Resolver) VisitAssertStmt(stmt *ast.AssertStmt) (interface{}, error) {
+	return nil, r.resolveAssertion(stmt.Assertion)
+}
+
+func (r *Resolver) resolveAssertion(assertion *ast.Assertion) error {
+	if _, err := r.resolveExpr(assertion.Condition); err != nil {
+		return err
+	}
+	if assertion.Message != nil {
+		if _, err := r.resolveExpr(assertion.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *Resolver) VisitWhileStmt(stmt *ast.WhileStmt) (interface{}, error) {
+	r.checkCondition(stmt.Condition, true)
+	r.loopDepth++
+	defer func() { r.loopDepth-- }()
 	_, err := r.resolveExpr(stmt.Condition)
 	if err != nil {
 		return nil, err
@@ -184,6 +283,68 @@ func (r *Resolver) VisitWhileStmt(stmt *ast.WhileStmt) (interface{}, error) {
 	return nil, nil
 }
 
+func (r *Resolver) VisitForInStmt(stmt *ast.ForInStmt) (interface{}, error) {
+	_, err := r.resolveExpr(stmt.Iterable)
+	if err != nil {
+		return nil, err
+	}
+
+	r.loopDepth++
+	r.beginScope()
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+	_, err = r.resolveStmt(stmt.Body)
+	r.endScope()
+	r.loopDepth--
+	return nil, err
+}
+
+func (r *Resolver) VisitSpawnExpr(expr *ast.Spawn) (interface{}, error) {
+	return r.resolveExpr(expr.Call)
+}
+
+func (r *Resolver) VisitSelectStmt(stmt *ast.SelectStmt) (interface{}, error) {
+	for _, selectCase := range stmt.Cases {
+		_, err := r.resolveExpr(selectCase.Channel)
+		if err != nil {
+			return nil, err
+		}
+		if selectCase.Value != nil {
+			_, err = r.resolveExpr(selectCase.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		r.beginScope()
+		if selectCase.Name != nil {
+			r.declare(*selectCase.Name)
+			r.define(*selectCase.Name)
+		}
+		_, err = r.resolveStmt(selectCase.Body)
+		r.endScope()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if stmt.Default != nil {
+		_, err := r.resolveStmt(stmt.Default)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitAwaitExpr(expr *ast.Await) (interface{}, error) {
+	// Top-level code may await; it blocks on the event loop instead.
+	if r.currentFunction != FunctionTypeNone && !r.inAsync {
+		r.error(expr.Keyword, errors.CodeInvalidPlacement, "Can't use 'await' outside an async function.")
+	}
+	return r.resolveExpr(expr.Value)
+}
+
 func (r *Resolver) VisitBinaryExpr(expr *ast.Binary) (interface{}, error) {
 	_, err := r.resolveExpr(expr.Left)
 	if err != nil {
@@ -213,11 +374,14 @@ func (r *Resolver) VisitVariableExpr(expr *ast.Variable) (interface{}, error) {
 		scope := r.scopes[len(r.scopes)-1]
 		if defined, ok := scope[expr.Name.Lexeme]; ok && !defined {
 			// Variable is declared but not yet defined
-			return nil, fmt.Errorf("Cannot read local variable '%s' in its own initializer.", expr.Name.Lexeme)
+			r.error(expr.Name, errors.CodeSelfReference,
+				fmt.Sprintf("Can't read local variable '%s' in its own initializer.", expr.Name.Lexeme))
+			return nil, nil
 		}
 	}
 
 	r.resolveLocal(expr, expr.Name)
+	r.markRead(expr.Name)
 	return nil, nil
 }
 
@@ -227,7 +391,10 @@ func (r *Resolver) VisitAssignExpr(expr *ast.Assign) (interface{}, error) {
 		return nil, err
 	}
 
-	r.resolveLocal(expr, expr.Name)
+	if !r.resolveLocal(expr, expr.Name) {
+		r.reassigned[expr.Name.Lexeme] = true
+	}
+	r.markAssigned(expr.Name)
 	return nil, nil
 }
 
@@ -236,6 +403,9 @@ func (r *Resolver) VisitCallExpr(expr *ast.Call) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+	if callee, ok := expr.Callee.(*ast.Variable); ok && r.findScope(callee.Name) < 0 {
+		r.calls = append(r.calls, callSite{name: callee.Name, paren: expr.Paren, arguments: len(expr.Arguments)})
+	}
 
 	for _, arg := range expr.Arguments {
 		_, err := r.resolveExpr(arg)
@@ -254,10 +424,30 @@ func (r *Resolver) VisitClassStmt(stmt *ast.ClassStmt) (interface{}, error) {
 	r.define(stmt.Name)
 
 	if stmt.Superclass != nil && stmt.Name.Lexeme == stmt.Superclass.Name.Lexeme {
-		return nil, fmt.Errorf("A class cannot inherit from itself.")
+		r.error(stmt.Superclass.Name, errors.CodeSelfInheritance, "A class can't inherit from itself.")
+	}
+
+	// Decorators, including those on methods, are evaluated in the scope
+	// enclosing the class, outside of any 'this' or 'super'.
+	r.currentClass = enclosingClass
+	err := r.resolveDecorators(stmt.Decorators)
+	if err != nil {
+		return nil, err
 	}
+	for _, method := range stmt.Methods {
+		err = r.resolveDecorators(method.Decorators)
+		if err != nil {
+			return nil, err
+		}
+	}
+	r.currentClass = ClassTypeClass
+	defer func() { r.currentClass = enclosingClass }()
 
 	if stmt.Superclass != nil {
+		superclass := stmt.Superclass.Name.Lexeme
+		r.subclasses[superclass] = append(r.subclasses[superclass], stmt.Name.Lexeme)
+		r.superclasses[stmt.Name.Lexeme] = superclass
+
 		r.currentClass = ClassTypeSubclass
 		_, err := r.resolveExpr(stmt.Superclass)
 		if err != nil {
@@ -265,36 +455,44 @@ func (r *Resolver) VisitClassStmt(stmt *ast.ClassStmt) (interface{}, error) {
 		}
 
 		r.beginScope()
+		defer r.endScope()
 		r.scopes[len(r.scopes)-1]["super"] = true
 	}
 
 	r.beginScope()
+	defer r.endScope()
 	r.scopes[len(r.scopes)-1]["this"] = true
 
 	for _, method := range stmt.Methods {
 		declaration := FunctionTypeMethod
 		if method.Name.Lexeme == "init" {
 			declaration = FunctionTypeInitializer
+			if method.IsAsync {
+				r.error(method.Name, errors.CodeInvalidPlacement, "Can't make an initializer async.")
+			}
 		}
 		err := r.resolveFunction(method, declaration)
 		if err != nil {
 			return nil, err
 		}
 	}
+	return nil, nil
+}
 
-	r.endScope()
-
-	if stmt.Superclass != nil {
-		r.endScope()
+func (r *Resolver) resolveDecorators(decorators []*ast.Decorator) error {
+	for _, decorator := range decorators {
+		_, err := r.resolveExpr(decorator.Expression)
+		if err != nil {
+			return err
+		}
 	}
-
-	r.currentClass = enclosingClass
-	return nil, nil
+	return nil
 }
 
 func (r *Resolver) VisitThisExpr(expr *ast.This) (interface{}, error) {
 	if r.currentClass == ClassTypeNone {
-		return nil, fmt.Errorf("Can't use 'this' outside of a class.")
+		r.error(expr.Keyword, errors.CodeInvalidPlacement, "Can't use 'this' outside of a class.")
+		return nil, nil
 	}
 	r.resolveLocal(expr, expr.Keyword)
 	return nil, nil
@@ -302,34 +500,237 @@ func (r *Resolver) VisitThisExpr(expr *ast.This) (interface{}, error) {
 
 func (r *Resolver) VisitSuperExpr(expr *ast.Super) (interface{}, error) {
 	if r.currentClass == ClassTypeNone {
-		return nil, fmt.Errorf("Can't use 'super' outside of a class.")
+		r.error(expr.Keyword, errors.CodeInvalidPlacement, "Can't use 'super' outside of a class.")
+		return nil, nil
 	} else if r.currentClass != ClassTypeSubclass {
-		return nil, fmt.Errorf("Can't use 'super' in a class with no superclass.")
+		r.error(expr.Keyword, errors.CodeInvalidPlacement, "Can't use 'super' in a class with no superclass.")
+		return nil, nil
 	}
 	r.resolveLocal(expr, expr.Keyword)
 	return nil, nil
 }
 
+func (r *Resolver) VisitMatchStmt(stmt *ast.MatchStmt) (interface{}, error) {
+	return nil, r.resolveMatch(stmt.Keyword, stmt.Subject, stmt.Cases)
+}
+
+func (r *Resolver) VisitMatchExpr(expr *ast.Match) (interface{}, error) {
+	return nil, r.resolveMatch(expr.Keyword, expr.Subject, expr.Cases)
+}
+
+func (r *Resolver) resolveMatch(keyword scanner.Token, subject ast.Expr, cases []*ast.MatchCase) error {
+	_, err := r.resolveExpr(subject)
+	if err != nil {
+		return err
+	}
+
+	for _, matchCase := range cases {
+		// Each case gets its own scope holding the names its patterns bind.
+		r.beginScope()
+		err := r.resolveMatchCase(matchCase)
+		r.endScope()
+		if err != nil {
+			return err
+		}
+	}
+
+	r.matches = append(r.matches, matchSite{keyword: keyword, cases: cases})
+	return nil
+}
+
+func (r *Resolver) resolveMatchCase(matchCase *ast.MatchCase) error {
+	for _, pattern := range matchCase.Patterns {
+		err := r.resolvePattern(pattern)
+		if err != nil {
+			return err
+		}
+	}
+	if matchCase.Guard != nil {
+		_, err := r.resolveExpr(matchCase.Guard)
+		if err != nil {
+			return err
+		}
+	}
+	var err error
+	if matchCase.Body != nil {
+		_, err = r.resolveStmt(matchCase.Body)
+	} else {
+		_, err = r.resolveExpr(matchCase.Value)
+	}
+	return err
+}
+
+func (r *Resolver) resolvePattern(pattern ast.Pattern) error {
+	switch pat := pattern.(type) {
+	case *ast.BindingPattern:
+		r.declare(pat.Name)
+		r.define(pat.Name)
+	case *ast.ClassPattern:
+		_, err := r.resolveExpr(pat.Class)
+		if err != nil {
+			return err
+		}
+		for _, field := range pat.Fields {
+			err := r.resolvePattern(field)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkExhaustiveness treats a superclass and its direct subclasses as an
+// enum. A match whose class patterns name one of those subclasses must cover
+// all of them, or have a catch-all case, to be considered exhaustive.
+func (r *Resolver) checkExhaustiveness() {
+	for _, site := range r.matches {
+		covered := make(map[string]bool)
+		enum := ""
+		catchAll := false
+		for _, matchCase := range site.cases {
+			for _, pattern := range matchCase.Patterns {
+				switch pat := pattern.(type) {
+				case *ast.WildcardPattern, *ast.BindingPattern:
+					if matchCase.Guard == nil {
+						catchAll = true
+					}
+				case *ast.ClassPattern:
+					name := pat.Class.Name.Lexeme
+					if enum == "" {
+						enum = r.superclasses[name]
+					}
+					if matchCase.Guard == nil && irrefutable(pat.Fields) {
+						covered[name] = true
+					}
+				}
+			}
+		}
+		if catchAll || enum == "" || covered[enum] {
+			continue
+		}
+
+		var missing []string
+		for _, variant := range r.subclasses[enum] {
+			if !covered[variant] {
+				missing = append(missing, "'"+variant+"'")
+			}
+		}
+		if len(missing) > 0 {
+			r.warn(site.keyword.Diagnostic(errors.CodeNonExhaustiveMatch, fmt.Sprintf(
+				"Match over '%s' is not exhaustive; missing %s.", enum, strings.Join(missing, ", "))))
+		}
+	}
+}
+
+func irrefutable(patterns []ast.Pattern) bool {
+	for _, pattern := range patterns {
+		switch pattern.(type) {
+		case *ast.WildcardPattern, *ast.BindingPattern:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Resolver) VisitListExpr(expr *ast.List) (interface{}, error) {
+	for _, element := range expr.Elements {
+		_, err := r.resolveExpr(element)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitTupleExpr(expr *ast.Tuple) (interface{}, error) {
+	for _, element := range expr.Elements {
+		_, err := r.resolveExpr(element)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitIndexExpr(expr *ast.Index) (interface{}, error) {
+	_, err := r.resolveExpr(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	return r.resolveExpr(expr.Index)
+}
+
+func (r *Resolver) VisitIndexSetExpr(expr *ast.IndexSet) (interface{}, error) {
+	_, err := r.resolveExpr(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+	_, err = r.resolveExpr(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	return r.resolveExpr(expr.Index)
+}
+
+func (r *Resolver) VisitDestructureAssignExpr(expr *ast.DestructureAssign) (interface{}, error) {
+	_, err := r.resolveExpr(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, target := range expr.Targets {
+		if !r.resolveLocal(target, target.Name) {
+			r.reassigned[target.Name.Lexeme] = true
+		}
+		r.markAssigned(target.Name)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitDestructureVarStmt(stmt *ast.DestructureVarStmt) (interface{}, error) {
+	names := stmt.Names
+	if stmt.Rest != nil {
+		names = append(names[:len(names):len(names)], *stmt.Rest)
+	}
+
+	for _, name := range names {
+		r.declare(name)
+	}
+	_, err := r.resolveExpr(stmt.Initializer)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		r.define(name)
+	}
+	return nil, nil
+}
+
 func (r *Resolver) beginScope() {
 	r.scopes = append(r.scopes, make(map[string]bool))
+	r.locals = append(r.locals, make(map[string]*local))
 }
 
 func (r *Resolver) endScope() {
+	r.checkLocals(r.locals[len(r.locals)-1])
 	r.scopes = r.scopes[:len(r.scopes)-1]
+	r.locals = r.locals[:len(r.locals)-1]
 }
 
-func (r *Resolver) declare(name scanner.Token) error {
+func (r *Resolver) declare(name scanner.Token) {
 	if len(r.scopes) == 0 {
-		return nil
+		return
 	}
 
 	scope := r.scopes[len(r.scopes)-1]
 	if _, exists := scope[name.Lexeme]; exists {
-		return fmt.Errorf("Already a variable with this name in this scope.")
+		r.error(name, errors.CodeDuplicateDeclaration, "Already a variable with this name in this scope.")
 	}
 
 	scope[name.Lexeme] = false
-	return nil
+	r.trackLocal(name)
 }
 
 func (r *Resolver) define(name scanner.Token) {
@@ -340,41 +741,89 @@ func (r *Resolver) define(name scanner.Token) {
 	scope[name.Lexeme] = true
 }
 
-func (r *Resolver) resolveLocal(expr ast.Expr, name scanner.Token) {
+// resolveLocal tells the interpreter which scope name refers to, reporting
+// whether it is a local. Any other name is taken to be a global.
+func (r *Resolver) resolveLocal(expr ast.Expr, name scanner.Token) bool {
+	i := r.findScope(name)
+	if i < 0 {
+		r.globalUses = append(r.globalUses, name)
+		return false
+	}
+	if !r.scopes[i][name.Lexeme] {
+		r.error(name, errors.CodeSelfReference,
+			fmt.Sprintf("Can't read local variable '%s' in its own initializer.", name.Lexeme))
+	}
+	r.interpreter.Resolve(expr, len(r.scopes)-1-i)
+	return true
+}
+
+// findScope returns the index of the innermost scope declaring name, or -1
+// if no scope does.
+func (r *Resolver) findScope(name scanner.Token) int {
 	for i := len(r.scopes) - 1; i >= 0; i-- {
-		if isDeclared, exists := r.scopes[i][name.Lexeme]; exists {
-			if !isDeclared {
-				errors.Error(name.Line, "Can't read local variable in its own initializer.")
-			}
-			r.interpreter.Resolve(expr, len(r.scopes)-1-i)
-			return
+		if _, exists := r.scopes[i][name.Lexeme]; exists {
+			return i
 		}
 	}
+	return -1
 }
 
 func (r *Resolver) resolveFunction(function *ast.FunctionStmt, functionType FunctionType) error {
 	enclosingFunction := r.currentFunction
+	enclosingAsync := r.inAsync
+	enclosingEnsures := r.inEnsures
 	r.currentFunction = functionType
+	r.inAsync = function.IsAsync
+	r.inEnsures = len(function.Ensures) > 0
+	r.functionDepth++
 
 	r.beginScope()
 	for _, param := range function.Params {
-		err := r.declare(param)
-		if err != nil {
-			return err
-		}
+		r.declare(param)
 		r.define(param)
+		if l := r.locals[len(r.locals)-1][param.Lexeme]; l != nil {
+			l.parameter = true
+		}
 	}
-	err := r.resolveStatements(function.Body)
-	if err != nil {
-		return err
+	err := r.resolveContracts(function)
+	if err == nil {
+		err = r.resolveStatements(function.Body)
 	}
 	r.endScope()
 
+	r.functionDepth--
 	r.currentFunction = enclosingFunction
+	r.inAsync = enclosingAsync
+	r.inEnsures = enclosingEnsures
+	return err
+}
+
+// resolveContracts resolves a function's requires and ensures clauses in the
+// scope of its parameters. Ensures clauses get an inner scope binding
+// 'result', which the interpreter creates around the returned value.
+func (r *Resolver) resolveContracts(function *ast.FunctionStmt) error {
+	for _, clause := range function.Requires {
+		if err := r.resolveAssertion(clause); err != nil {
+			return err
+		}
+	}
+	if len(function.Ensures) == 0 {
+		return nil
+	}
+
+	r.beginScope()
+	defer r.endScope()
+	r.scopes[len(r.scopes)-1]["result"] = true
+	for _, clause := range function.Ensures {
+		if err := r.resolveAssertion(clause); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (r *Resolver) resolveStatements(statements []ast.Stmt) error {
+	r.checkReachable(statements)
 	for _, stmt := range statements {
 		_, err := r.resolveStmt(stmt)
 		if err != nil {
@@ -0,0 +1,204 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chase-compton/LOX_GO/ast"
+	"github.com/chase-compton/LOX_GO/errors"
+	"github.com/chase-compton/LOX_GO/scanner"
+)
+
+// local is what the warnings pass knows about a local variable.
+type local struct {
+	name      scanner.Token
+	parameter bool
+	read      bool
+	// function and loop are how deeply functions and loops were nested
+	// where the variable was declared.
+	function int
+	loop     int
+	// assignments are the assignments to the variable that no read has
+	// followed yet.
+	assignments []scanner.Token
+}
+
+// EnableWarning turns on the warning with the given code. Warnings are off
+// by default.
+func (r *Resolver) EnableWarning(code string) {
+	r.warnings[code] = true
+}
+
+// warn queues a warning unless it is disabled or an ignore comment silences
+// it. Warnings are found out of order, so they're held until
+// reportWarnings.
+func (r *Resolver) warn(d errors.Diagnostic) {
+	d.Severity = errors.SeverityWarning
+	if !r.warnings[d.Code] || r.Suppressions.Suppresses(d) {
+		return
+	}
+	r.pending = append(r.pending, d)
+}
+
+// reportWarnings reports the queued warnings in source order.
+func (r *Resolver) reportWarnings() {
+	sort.SliceStable(r.pending, func(i, j int) bool {
+		a, b := r.pending[i], r.pending[j]
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+	for _, d := range r.pending {
+		r.diagnostics.Report(d)
+	}
+	r.pending = nil
+}
+
+// collectGlobals records the names declared at the top level, wherever they
+// appear, so locals can be checked against them.
+func (r *Resolver) collectGlobals(statements []ast.Stmt) {
+	declare := func(name scanner.Token, stmt ast.Stmt) {
+		r.globals[name.Lexeme] = true
+		r.declarations[name.Lexeme] = append(r.declarations[name.Lexeme], stmt)
+	}
+	for _, stmt := range statements {
+		switch s := stmt.(type) {
+		case *ast.VarStmt:
+			declare(s.Name, s)
+		case *ast.FunctionStmt:
+			declare(s.Name, s)
+		case *ast.ClassStmt:
+			declare(s.Name, s)
+		case *ast.DestructureVarStmt:
+			for _, name := range s.Names {
+				declare(name, s)
+			}
+			if s.Rest != nil {
+				declare(*s.Rest, s)
+			}
+		}
+	}
+}
+
+// trackLocal starts tracking a variable just declared in the innermost
+// scope, warning if it hides another variable of the same name.
+func (r *Resolver) trackLocal(name scanner.Token) {
+	if ignored(name) {
+		return
+	}
+	if r.shadows(name.Lexeme) {
+		r.warn(name.Diagnostic(errors.CodeShadowedVariable,
+			fmt.Sprintf("Local '%s' shadows an outer variable.", name.Lexeme)))
+	}
+	r.locals[len(r.locals)-1][name.Lexeme] = &local{
+		name:     name,
+		function: r.functionDepth,
+		loop:     r.loopDepth,
+	}
+}
+
+func (r *Resolver) shadows(name string) bool {
+	for i := len(r.scopes) - 2; i >= 0; i-- {
+		if _, ok := r.scopes[i][name]; ok {
+			return true
+		}
+	}
+	return r.globals[name]
+}
+
+// findLocal returns the tracked local that name refers to, or nil for a
+// global or an untracked name such as 'this'.
+func (r *Resolver) findLocal(name scanner.Token) *local {
+	if i := r.findScope(name); i >= 0 {
+		return r.locals[i][name.Lexeme]
+	}
+	return nil
+}
+
+func (r *Resolver) markRead(name scanner.Token) {
+	if l := r.findLocal(name); l != nil {
+		l.read = true
+		l.assignments = nil
+	}
+}
+
+// markAssigned records an assignment that may turn out to be dead. Without
+// following control flow, that's only certain for straight-line code: an
+// assignment in a loop may be read on the next iteration, and one in a
+// closure may be read by the next call.
+func (r *Resolver) markAssigned(name scanner.Token) {
+	l := r.findLocal(name)
+	if l == nil || l.function != r.functionDepth || l.loop != r.loopDepth {
+		return
+	}
+	l.assignments = append(l.assignments, name)
+}
+
+// checkLocals warns about the variables of a scope that is ending which were
+// never read, or whose last assignments were never read.
+func (r *Resolver) checkLocals(locals map[string]*local) {
+	sorted := make([]*local, 0, len(locals))
+	for _, l := range locals {
+		sorted = append(sorted, l)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].name.Offset < sorted[j].name.Offset
+	})
+
+	for _, l := range sorted {
+		switch {
+		case !l.read && l.parameter:
+			r.warn(l.name.Diagnostic(errors.CodeUnusedParameter,
+				fmt.Sprintf("Parameter '%s' is never used.", l.name.Lexeme)))
+		case !l.read:
+			r.warn(l.name.Diagnostic(errors.CodeUnusedVariable,
+				fmt.Sprintf("Local variable '%s' is never used.", l.name.Lexeme)))
+		default:
+			for _, assignment := range l.assignments {
+				r.warn(assignment.Diagnostic(errors.CodeDeadAssignment,
+					fmt.Sprintf("Value assigned to '%s' is never read.", assignment.Lexeme)))
+			}
+		}
+	}
+}
+
+// checkReachable warns about the first statement of a block that follows a
+// return, since it can never run.
+func (r *Resolver) checkReachable(statements []ast.Stmt) {
+	for n, stmt := range statements[:max(len(statements)-1, 0)] {
+		if _, ok := stmt.(*ast.ReturnStmt); ok {
+			r.warn(statements[n+1].SourceSpan().Diagnostic(errors.CodeUnreachableCode, "Unreachable code."))
+			return
+		}
+	}
+}
+
+// checkCondition warns about an if or while condition that is a literal.
+// `while (true)`, which is how an endless loop is written, is left alone.
+func (r *Resolver) checkCondition(condition ast.Expr, loop bool) {
+	for {
+		grouping, ok := condition.(*ast.Grouping)
+		if !ok {
+			break
+		}
+		condition = grouping.Expression
+	}
+	literal, ok := condition.(*ast.Literal)
+	if !ok {
+		return
+	}
+	truthy := literal.Value != nil && literal.Value != false
+	if loop && literal.Value == true {
+		return
+	}
+	message := "Condition is always false."
+	if truthy {
+		message = "Condition is always true."
+	}
+	r.warn(condition.SourceSpan().Diagnostic(errors.CodeConstantCondition, message))
+}
+
+// ignored reports whether a variable is named to show it is deliberately
+// unused, like '_' or '_unused'.
+func ignored(name scanner.Token) bool {
+	return strings.HasPrefix(name.Lexeme, "_")
+}
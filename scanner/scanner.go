@@ -7,55 +7,98 @@ import (
 )
 
 type Scanner struct {
-	source  string
-	tokens  []Token
-	start   int
-	current int
-	line    int
+	diagnostics errors.Diagnostics
+	source      string
+	tokens      []Token
+	start       int
+	current     int
+	line        int
+	// column is the column of the next character. startLine and
+	// startColumn record where the current token began.
+	column      int
+	startLine   int
+	startColumn int
+	// suppressions holds the `// lox:ignore` comments seen.
+	suppressions errors.Suppressions
 }
 
 var keywords = map[string]TokenType{
-	"and":    AND,
-	"class":  CLASS,
-	"else":   ELSE,
-	"false":  FALSE,
-	"for":    FOR,
-	"fun":    FUN,
-	"if":     IF,
-	"nil":    NIL,
-	"or":     OR,
-	"print":  PRINT,
-	"return": RETURN,
-	"super":  SUPER,
-	"this":   THIS,
-	"true":   TRUE,
-	"var":    VAR,
-	"while":  WHILE,
+	"and":      AND,
+	"assert":   ASSERT,
+	"async":    ASYNC,
+	"await":    AWAIT,
+	"case":     CASE,
+	"class":    CLASS,
+	"else":     ELSE,
+	"ensures":  ENSURES,
+	"false":    FALSE,
+	"for":      FOR,
+	"fun":      FUN,
+	"if":       IF,
+	"in":       IN,
+	"match":    MATCH,
+	"nil":      NIL,
+	"or":       OR,
+	"print":    PRINT,
+	"requires": REQUIRES,
+	"return":   RETURN,
+	"select":   SELECT,
+	"spawn":    SPAWN,
+	"super":    SUPER,
+	"this":     THIS,
+	"true":     TRUE,
+	"var":      VAR,
+	"while":    WHILE,
 }
 
-func NewScanner(source string) *Scanner {
+// NewScanner returns a scanner for source that reports problems to
+// diagnostics.
+func NewScanner(source string, diagnostics errors.Diagnostics) *Scanner {
 	return &Scanner{
-		source: source,
-		tokens: []Token{},
-		line:   1,
+		diagnostics:  diagnostics,
+		source:       source,
+		tokens:       []Token{},
+		line:         1,
+		column:       1,
+		suppressions: make(errors.Suppressions),
 	}
 }
 
 func (s *Scanner) ScanTokens() []Token {
 	for !s.isAtEnd() {
 		s.start = s.current
+		s.startLine = s.line
+		s.startColumn = s.column
 		s.scanToken()
 	}
 
 	// Add an EOF token at the end.
 	s.tokens = append(s.tokens, Token{
-		Type: EOF,
-		Line: s.line,
+		Type:   EOF,
+		Line:   s.line,
+		Column: s.column,
+		Offset: len(s.source),
 	})
 
 	return s.tokens
 }
 
+// startsLine reports whether the current lexeme is the first thing on its
+// line.
+func (s *Scanner) startsLine() bool {
+	for n := s.start - 1; n >= 0 && s.source[n] != '\n'; n-- {
+		if s.source[n] != ' ' && s.source[n] != '\t' && s.source[n] != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// Suppressions returns the `// lox:ignore` comments found by ScanTokens.
+func (s *Scanner) Suppressions() errors.Suppressions {
+	return s.suppressions
+}
+
 func (s *Scanner) scanToken() {
 	c := s.advance()
 	switch c {
@@ -68,10 +111,20 @@ func (s *Scanner) scanToken() {
 		s.addToken(LEFT_BRACE, nil)
 	case '}':
 		s.addToken(RIGHT_BRACE, nil)
+	case '[':
+		s.addToken(LEFT_BRACKET, nil)
+	case ']':
+		s.addToken(RIGHT_BRACKET, nil)
 	case ',':
 		s.addToken(COMMA, nil)
 	case '.':
-		s.addToken(DOT, nil)
+		if s.peek() == '.' && s.peekNext() == '.' {
+			s.advance()
+			s.advance()
+			s.addToken(DOT_DOT_DOT, nil)
+		} else {
+			s.addToken(DOT, nil)
+		}
 	case '-':
 		s.addToken(MINUS, nil)
 	case '+':
@@ -80,6 +133,12 @@ func (s *Scanner) scanToken() {
 		s.addToken(SEMICOLON, nil)
 	case '*':
 		s.addToken(STAR, nil)
+	case '@':
+		s.addToken(AT, nil)
+	case ':':
+		s.addToken(COLON, nil)
+	case '|':
+		s.addToken(PIPE, nil)
 	// Operators (two-character tokens)
 	case '!':
 		if s.match('=') {
@@ -90,6 +149,8 @@ func (s *Scanner) scanToken() {
 	case '=':
 		if s.match('=') {
 			s.addToken(EQUAL_EQUAL, nil)
+		} else if s.match('>') {
+			s.addToken(ARROW, nil)
 		} else {
 			s.addToken(EQUAL, nil)
 		}
@@ -112,6 +173,9 @@ func (s *Scanner) scanToken() {
 			for s.peek() != '\n' && !s.isAtEnd() {
 				s.advance()
 			}
+			if codes, ok := errors.ParseIgnore(s.source[s.start+2 : s.current]); ok {
+				s.suppressions.Add(s.startLine, codes, s.startsLine())
+			}
 		} else {
 			s.addToken(SLASH, nil)
 		}
@@ -119,7 +183,7 @@ func (s *Scanner) scanToken() {
 	case ' ', '\r', '\t':
 		// Ignore whitespace.
 	case '\n':
-		s.line++
+		// advance() already moved to the next line.
 	// Strings
 	case '"':
 		s.string()
@@ -129,21 +193,22 @@ func (s *Scanner) scanToken() {
 		} else if isDigit(c) {
 			s.number()
 		} else {
-			errors.Error(s.line, "Unexpected character.")
+			// Report a multi-byte character once, not once per byte.
+			for isContinuationByte(s.peek()) {
+				s.advance()
+			}
+			s.error(errors.CodeUnexpectedCharacter, "Unexpected character.")
 		}
 	}
 }
 
 func (s *Scanner) string() {
 	for s.peek() != '"' && !s.isAtEnd() {
-		if s.peek() == '\n' {
-			s.line++
-		}
 		s.advance()
 	}
 
 	if s.isAtEnd() {
-		errors.Error(s.line, "Unterminated string.")
+		s.error(errors.CodeUnterminatedString, "Unterminated string.")
 		return
 	}
 
@@ -175,16 +240,36 @@ func (s *Scanner) number() {
 	var err error
 	value, err = strconv.ParseFloat(valueStr, 64)
 	if err != nil {
-		errors.Error(s.line, "Invalid number.")
+		s.error(errors.CodeInvalidNumber, "Invalid number.")
 		return
 	}
 
 	s.addToken(NUMBER, value)
 }
 
+// error reports a problem with the token being scanned, pointing at its
+// first character.
+func (s *Scanner) error(code, message string) {
+	s.diagnostics.Report(errors.Diagnostic{
+		Code:    code,
+		Span:    errors.Span{Line: s.startLine, Column: s.startColumn, Length: 1},
+		Message: message,
+	})
+}
+
+// advance consumes one byte and keeps the line and column up to date. Only
+// the first byte of a UTF-8 character moves the column, so columns count
+// characters.
 func (s *Scanner) advance() byte {
+	c := s.source[s.current]
 	s.current++
-	return s.source[s.current-1]
+	if c == '\n' {
+		s.line++
+		s.column = 1
+	} else if !isContinuationByte(c) {
+		s.column++
+	}
+	return c
 }
 
 func (s *Scanner) addToken(tokenType TokenType, literal interface{}) {
@@ -193,7 +278,10 @@ func (s *Scanner) addToken(tokenType TokenType, literal interface{}) {
 		Type:    tokenType,
 		Lexeme:  text,
 		Literal: literal,
-		Line:    s.line,
+		Line:    s.startLine,
+		Column:  s.startColumn,
+		Offset:  s.start,
+		Length:  s.current - s.start,
 	})
 }
 
@@ -222,7 +310,7 @@ func (s *Scanner) match(expected byte) bool {
 	if s.source[s.current] != expected {
 		return false
 	}
-	s.current++
+	s.advance()
 	return true
 }
 
@@ -1,14 +1,84 @@
 package scanner
 
-import "fmt"
+import (
+    "fmt"
+    "unicode/utf8"
+
+    "github.com/chase-compton/LOX_GO/errors"
+)
 
 type Token struct {
     Type    TokenType
     Lexeme  string
     Literal interface{}
     Line    int
+    // Column is the 1-based column of the token's first character, counted
+    // in characters rather than bytes.
+    Column int
+    // Offset and Length locate the lexeme in the source, in bytes.
+    Offset int
+    Length int
+}
+
+// Position is a point in the source. Line and Column count from 1, with the
+// column in characters; Offset is the 0-based byte offset.
+type Position struct {
+    Line   int
+    Column int
+    Offset int
 }
 
 func (t Token) String() string {
     return fmt.Sprintf("%v %s %v", t.Type, t.Lexeme, t.Literal)
 }
+
+// Start is the position of the token's first character.
+func (t Token) Start() Position {
+    return Position{Line: t.Line, Column: t.Column, Offset: t.Offset}
+}
+
+// End is the position just past the token's last character. Only strings
+// can span lines, so the end line and column come from the lexeme.
+func (t Token) End() Position {
+    end := Position{Line: t.Line, Column: t.Column, Offset: t.Offset + t.Length}
+    for _, r := range t.Lexeme {
+        if r == '\n' {
+            end.Line++
+            end.Column = 1
+        } else {
+            end.Column++
+        }
+    }
+    return end
+}
+
+// Width is how many characters of the token are on its first line, and at
+// least one so that even the EOF token can be pointed at.
+func (t Token) Width() int {
+    width := 0
+    for _, r := range t.Lexeme {
+        if r == '\n' {
+            break
+        }
+        width++
+    }
+    if width == 0 {
+        return 1
+    }
+    return width
+}
+
+// Diagnostic returns an error diagnostic that points at the token.
+func (t Token) Diagnostic(code, message string) errors.Diagnostic {
+    return errors.Diagnostic{
+        Code:    code,
+        Span:    errors.Span{Line: t.Line, Column: t.Column, Length: t.Width()},
+        Message: message,
+    }
+}
+
+// isContinuationByte reports whether b continues a multi-byte UTF-8
+// character rather than starting one.
+func isContinuationByte(b byte) bool {
+    return !utf8.RuneStart(b)
+}
@@ -10,13 +10,19 @@ const (
     RIGHT_PAREN
     LEFT_BRACE
     RIGHT_BRACE
+    LEFT_BRACKET
+    RIGHT_BRACKET
     COMMA
     DOT
+    DOT_DOT_DOT
     MINUS
     PLUS
     SEMICOLON
     SLASH
     STAR
+    AT
+    COLON
+    PIPE
 
     // One or two character tokens.
     BANG
@@ -27,6 +33,7 @@ const (
     GREATER_EQUAL
     LESS
     LESS_EQUAL
+    ARROW
 
     // Literals.
     IDENTIFIER
@@ -35,16 +42,26 @@ const (
 
     // Keywords.
     AND
+    ASSERT
+    ASYNC
+    AWAIT
+    CASE
     CLASS
     ELSE
+    ENSURES
     FALSE
     FUN
     FOR
     IF
+    IN
+    MATCH
     NIL
     OR
     PRINT
+    REQUIRES
     RETURN
+    SELECT
+    SPAWN
     SUPER
     THIS
     TRUE
@@ -59,13 +76,19 @@ var tokenTypeNames = []string{
     "RIGHT_PAREN",
     "LEFT_BRACE",
     "RIGHT_BRACE",
+    "LEFT_BRACKET",
+    "RIGHT_BRACKET",
     "COMMA",
     "DOT",
+    "DOT_DOT_DOT",
     "MINUS",
     "PLUS",
     "SEMICOLON",
     "SLASH",
     "STAR",
+    "AT",
+    "COLON",
+    "PIPE",
     "BANG",
 	"BANG_EQUAL",
 	"EQUAL",
@@ -74,20 +97,31 @@ var tokenTypeNames = []string{
 	"GREATER_EQUAL",
 	"LESS",
 	"LESS_EQUAL",
+	"ARROW",
 	"IDENTIFIER",
 	"STRING",
 	"NUMBER",
 	"AND",
+	"ASSERT",
+	"ASYNC",
+	"AWAIT",
+	"CASE",
 	"CLASS",
 	"ELSE",
+	"ENSURES",
 	"FALSE",
 	"FUN",
 	"FOR",
 	"IF",
+	"IN",
+	"MATCH",
 	"NIL",
 	"OR",
 	"PRINT",
+	"REQUIRES",
 	"RETURN",
+	"SELECT",
+	"SPAWN",
 	"SUPER",
 	"THIS",
 	"TRUE",
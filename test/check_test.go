@@ -0,0 +1,109 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+)
+
+func checkErrors(t *testing.T, source string) []string {
+	t.Helper()
+	return analyze(t, source, nil, true, false)
+}
+
+func TestCheckReportsProblems(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{
+			name: "undefined global",
+			source: `fun f() { return missing; }
+print f();`,
+			want: []string{"1: E0102 Undefined variable 'missing'."},
+		},
+		{
+			name: "did you mean",
+			source: `var counter = 0;
+fun bump() { countr = countr + 1; }
+print lenn("abc");`,
+			want: []string{
+				"2: E0102 Undefined variable 'countr'. (did you mean 'counter'?)",
+				"2: E0102 Undefined variable 'countr'. (did you mean 'counter'?)",
+				"3: E0102 Undefined variable 'lenn'. (did you mean 'len'?)",
+			},
+		},
+		{
+			name: "function arity",
+			source: `fun add(a, b) { return a + b; }
+add(1);`,
+			want: []string{"2: E0403 Expected 2 arguments but got 1."},
+		},
+		{
+			name: "inherited init",
+			source: `class Base { init(a, b) {} }
+class Middle < Base {}
+class Leaf < Middle {}
+Leaf(1, 2, 3);`,
+			want: []string{"4: E0403 Expected 2 arguments but got 3."},
+		},
+		{
+			name: "class without init",
+			source: `class Empty {}
+Empty(1);`,
+			want: []string{"2: E0403 Expected 0 arguments but got 1."},
+		},
+		{
+			name: "in source order",
+			source: `fun one(a) {}
+one();
+print nowhere;
+one(1, 2);`,
+			want: []string{
+				"2: E0403 Expected 1 arguments but got 0.",
+				"3: E0102 Undefined variable 'nowhere'.",
+				"4: E0403 Expected 1 arguments but got 2.",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := checkErrors(t, test.source); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("reported %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckAcceptsUnknowableCalls(t *testing.T) {
+	tests := map[string]string{
+		"decorated function": `fun twice(f) { fun wrapper(a, b) { return f(a) + f(b); } return wrapper; }
+@twice
+fun double(n) { return n * 2; }
+print double(1, 2);`,
+		"decorated init": `fun keep(f) { return f; }
+class Box { @keep init(a) {} }
+Box(1, 2);`,
+		"decorated class": `fun keep(c) { return c; }
+@keep
+class Box { init(a) {} }
+Box();`,
+		"reassigned function": `fun f(a) {}
+f = clock;
+f();`,
+		"declared twice": `fun f(a) {}
+fun f(a, b) {}
+f(1, 2);`,
+		"defined later": `fun main() { helper(1); }
+fun helper(n) {}
+main();`,
+		"native globals": `print len("abc") + clock();`,
+	}
+	for name, source := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := checkErrors(t, source); len(got) > 0 {
+				t.Errorf("reported %q for a valid program", got)
+			}
+		})
+	}
+}
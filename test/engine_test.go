@@ -0,0 +1,615 @@
+package test
+
+import (
+	"bytes"
+	goerrors "errors"
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chase-compton/LOX_GO/interpreter"
+	"github.com/chase-compton/LOX_GO/pkg/lox"
+)
+
+func TestEngineRun(t *testing.T) {
+	engine := lox.NewEngine()
+	var output bytes.Buffer
+	engine.SetOutput(&output)
+
+	if err := engine.Run(`var greeting = "hello"; print greeting;`); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if output.String() != "hello\n" {
+		t.Errorf("printed %q, want %q", output.String(), "hello\n")
+	}
+
+	// Globals persist from one run to the next.
+	if err := engine.Run(`print greeting + " again";`); err != nil {
+		t.Fatalf("second Run failed: %v", err)
+	}
+	if output.String() != "hello\nhello again\n" {
+		t.Errorf("printed %q after second run", output.String())
+	}
+}
+
+func TestEngineEvalAndGlobals(t *testing.T) {
+	engine := lox.NewEngine()
+	engine.SetGlobal("base", 40.0)
+
+	value, err := engine.Eval("base + 2")
+	if err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	if value != 42.0 {
+		t.Errorf("Eval returned %v, want 42", value)
+	}
+
+	if err := engine.Run(`var doubled = base * 2;`); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if value, ok := engine.GetGlobal("doubled"); !ok || value != 80.0 {
+		t.Errorf("GetGlobal returned %v, %v; want 80, true", value, ok)
+	}
+	if _, ok := engine.GetGlobal("missing"); ok {
+		t.Errorf("GetGlobal found an undefined variable")
+	}
+}
+
+func TestEngineCall(t *testing.T) {
+	engine := lox.NewEngine()
+	if err := engine.Run(`fun add(a, b) { return a + b; }`); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	value, err := engine.Call("add", 1.0, 2.0)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if value != 3.0 {
+		t.Errorf("Call returned %v, want 3", value)
+	}
+
+	if _, err := engine.Call("add", 1.0); err == nil {
+		t.Errorf("Call with too few arguments succeeded")
+	}
+	if _, err := engine.Call("missing"); err == nil {
+		t.Errorf("Call of an undefined function succeeded")
+	}
+}
+
+func TestEngineErrors(t *testing.T) {
+	engine := lox.NewEngine()
+
+	var syntaxErr *lox.SyntaxError
+	if err := engine.Run(`print ;`); !goerrors.As(err, &syntaxErr) {
+		t.Errorf("got %v, want a SyntaxError", err)
+	}
+	if _, err := engine.Eval("1 +"); !goerrors.As(err, &syntaxErr) {
+		t.Errorf("got %v, want a SyntaxError", err)
+	}
+
+	var resolveErr *lox.ResolveError
+	if err := engine.Run(`return 1;`); !goerrors.As(err, &resolveErr) {
+		t.Errorf("got %v, want a ResolveError", err)
+	}
+
+	var typeErr *lox.TypeError
+	if err := engine.Run(`var n: num = "one";`); err != nil {
+		t.Errorf("got %v without type checking, want no error", err)
+	}
+	engine.SetTypeCheck(true)
+	if err := engine.Run(`var n: num = "one";`); !goerrors.As(err, &typeErr) {
+		t.Errorf("got %v, want a TypeError", err)
+	}
+	engine.SetTypeCheck(false)
+
+	var runtimeErr *lox.RuntimeError
+	err := engine.Run(`print 1 + "a";`)
+	if !goerrors.As(err, &runtimeErr) {
+		t.Fatalf("got %v, want a RuntimeError", err)
+	}
+	if runtimeErr.Diagnostic.Line != 1 {
+		t.Errorf("runtime error on line %d, want 1", runtimeErr.Diagnostic.Line)
+	}
+	var interpreterErr *interpreter.RuntimeError
+	if !goerrors.As(err, &interpreterErr) {
+		t.Errorf("RuntimeError doesn't wrap the interpreter's error")
+	}
+
+	// An error doesn't stop the engine from running more code.
+	if value, err := engine.Eval(`"still " + "working"`); err != nil || value != "still working" {
+		t.Errorf("Eval after errors returned %v, %v", value, err)
+	}
+}
+
+func TestEngineRegisterFunc(t *testing.T) {
+	engine := lox.NewEngine()
+	var output bytes.Buffer
+	engine.SetOutput(&output)
+
+	must(t, engine.RegisterFunc("repeat", strings.Repeat))
+	must(t, engine.RegisterFunc("sum", func(numbers ...int) int {
+		total := 0
+		for _, n := range numbers {
+			total += n
+		}
+		return total
+	}))
+	must(t, engine.RegisterFunc("divmod", func(a, b int) (int, int) { return a / b, a % b }))
+	must(t, engine.RegisterFunc("parse", func(s string) (float64, error) {
+		if s == "" {
+			return 0, fmt.Errorf("empty input")
+		}
+		return float64(len(s)), nil
+	}))
+
+	err := engine.Run(`
+print repeat("ab", 3);
+print sum(1, 2, 3);
+print divmod(7, 2);
+print parse("four");
+`)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if want := "ababab\n6\n(3, 1)\n4\n"; output.String() != want {
+		t.Errorf("printed %q, want %q", output.String(), want)
+	}
+
+	var runtimeErr *lox.RuntimeError
+	if err := engine.Run(`parse("");`); !goerrors.As(err, &runtimeErr) || runtimeErr.Diagnostic.Message != "empty input" {
+		t.Errorf("got %v, want a RuntimeError for the Go error", err)
+	}
+	if err := engine.Run(`repeat("ab", 1.5);`); err == nil ||
+		!strings.Contains(err.Error(), "Expected an integer for argument 2 of 'repeat' but got a number.") {
+		t.Errorf("got %v, want an argument conversion error", err)
+	}
+	if err := engine.RegisterFunc("notAFunction", 42); err == nil {
+		t.Errorf("RegisterFunc accepted a non-function")
+	}
+}
+
+type point struct {
+	X, Y   float64
+	Label  string `lox:"name"`
+	secret int
+}
+
+func (p *point) Scale(factor float64) {
+	p.X *= factor
+	p.Y *= factor
+}
+
+func (p point) Sum() float64 {
+	return p.X + p.Y
+}
+
+type counter struct {
+	Count int
+}
+
+func (c *counter) Init(start int) {
+	c.Count = start
+}
+
+func (c *counter) Increment() int {
+	c.Count++
+	return c.Count
+}
+
+func TestEngineRegisterType(t *testing.T) {
+	engine := lox.NewEngine()
+	var output bytes.Buffer
+	engine.SetOutput(&output)
+
+	must(t, engine.RegisterType("Point", point{}))
+	must(t, engine.RegisterType("Counter", (*counter)(nil)))
+	must(t, engine.RegisterFunc("origin", func() point { return point{Label: "origin"} }))
+	must(t, engine.RegisterFunc("describe", func(p *point) string { return fmt.Sprintf("%s(%g, %g)", p.Label, p.X, p.Y) }))
+
+	err := engine.Run(`
+var p = Point(1, 2, "p");
+p.scale(3);
+print p.x;
+print p.sum();
+p.name = "q";
+print describe(p);
+print origin().name;
+print p;
+
+var c = Counter(10);
+c.increment();
+print c.increment();
+`)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if want := "3\n9\nq(3, 6)\norigin\n<Point instance>\n12\n"; output.String() != want {
+		t.Errorf("printed %q, want %q", output.String(), want)
+	}
+
+	if err := engine.Run(`Point(1, 2, "p").secret;`); err == nil ||
+		!strings.Contains(err.Error(), "Undefined property 'secret'.") {
+		t.Errorf("got %v, want an undefined property error", err)
+	}
+	if err := engine.Run(`Point(1, 2, "p").x = "far";`); err == nil ||
+		!strings.Contains(err.Error(), "Expected a number for field 'x' but got a string.") {
+		t.Errorf("got %v, want a field conversion error", err)
+	}
+	if err := engine.RegisterType("Number", 42); err == nil {
+		t.Errorf("RegisterType accepted a non-struct")
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newStackClass returns a native class keeping its elements in Go, out of
+// sight of Lox code.
+func newStackClass() *interpreter.LoxClass {
+	elements := func(this *interpreter.LoxInstance) []interface{} {
+		stack, _ := this.State().([]interface{})
+		return stack
+	}
+	return interpreter.NewNativeClass("Stack", nil,
+		interpreter.NewNativeFunction("init", 0, func(_ *interpreter.Interpreter, this *interpreter.LoxInstance, _ []interface{}) (interface{}, error) {
+			this.SetState([]interface{}{})
+			return nil, nil
+		}),
+		interpreter.NewNativeFunction("push", 1, func(_ *interpreter.Interpreter, this *interpreter.LoxInstance, arguments []interface{}) (interface{}, error) {
+			this.SetState(append(elements(this), arguments[0]))
+			return nil, nil
+		}),
+		interpreter.NewNativeFunction("pop", 0, func(_ *interpreter.Interpreter, this *interpreter.LoxInstance, _ []interface{}) (interface{}, error) {
+			stack := elements(this)
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("Can't pop an empty stack.")
+			}
+			this.SetState(stack[:len(stack)-1])
+			return stack[len(stack)-1], nil
+		}),
+		interpreter.NewNativeFunction("size", 0, func(_ *interpreter.Interpreter, this *interpreter.LoxInstance, _ []interface{}) (interface{}, error) {
+			return float64(len(elements(this))), nil
+		}),
+	)
+}
+
+func TestNativeClass(t *testing.T) {
+	engine := lox.NewEngine()
+	var output bytes.Buffer
+	engine.SetOutput(&output)
+	engine.SetGlobal("Stack", newStackClass())
+
+	err := engine.Run(`
+var stack = Stack();
+stack.push(1);
+stack.push(2);
+print stack.pop();
+print stack.size();
+print stack;
+
+class CountingStack < Stack {
+  init(label) {
+    super.init();
+    this.label = label;
+    this.pushes = 0;
+  }
+
+  push(value) {
+    this.pushes = this.pushes + 1;
+    super.push(value);
+  }
+}
+
+var counting = CountingStack("counted");
+counting.push("a");
+counting.push("b");
+print counting.pushes;
+print counting.size();
+print counting.pop();
+print counting.label;
+print isInstance(counting, Stack);
+print fields(counting);
+`)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	want := "2\n1\n<Stack instance>\n2\n2\nb\ncounted\ntrue\n[label, pushes]\n"
+	if output.String() != want {
+		t.Errorf("printed %q, want %q", output.String(), want)
+	}
+
+	if err := engine.Run(`Stack().pop();`); err == nil || !strings.Contains(err.Error(), "Can't pop an empty stack.") {
+		t.Errorf("got %v, want the native method's error", err)
+	}
+	if err := engine.Run(`Stack().push();`); err == nil || !strings.Contains(err.Error(), "Expected 1 arguments but got 0.") {
+		t.Errorf("got %v, want an arity error", err)
+	}
+}
+
+func TestNativeClassWithoutSuperInit(t *testing.T) {
+	engine := lox.NewEngine()
+	var output bytes.Buffer
+	engine.SetOutput(&output)
+	engine.SetGlobal("Stack", newStackClass())
+
+	// Lazy never calls super.init(), so its native methods start from a nil
+	// State(), which the stack reads as empty.
+	err := engine.Run(`
+class Lazy < Stack {
+  init() {
+    this.label = "lazy";
+  }
+
+  push(value) {
+    super.push(value * 2);
+  }
+}
+
+var lazy = Lazy();
+print lazy.size();
+lazy.push(1);
+lazy.push(2);
+print lazy.pop();
+print lazy.size();
+
+match (lazy) {
+  case Lazy() => print "matched";
+}
+`)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	want := "0\n4\n1\nmatched\n"
+	if output.String() != want {
+		t.Errorf("printed %q, want %q", output.String(), want)
+	}
+
+	// A native initializer has no parameter names to match fields by.
+	err = engine.Run(`
+match (Stack()) {
+  case Stack(top) => print top;
+}
+`)
+	if err == nil || !strings.Contains(err.Error(), "Pattern for 'Stack' can't match fields without an undecorated Lox initializer.") {
+		t.Errorf("got %v, want an error about the native initializer", err)
+	}
+}
+
+func TestEngineTasksRunWhileResolving(t *testing.T) {
+	engine := lox.NewEngine()
+	must(t, engine.Run(`
+var done = Channel();
+fun busy() {
+  var total = 0;
+  for (var n = 0; n < 20000; n = n + 1) total = total + n;
+  done.send(total);
+}
+spawn busy();
+`))
+	// Resolving more code adds to the locals table the task is reading.
+	for n := 0; n < 50; n++ {
+		must(t, engine.Run(fmt.Sprintf(`fun f%d(a) { var b = a; return b; }`, n)))
+	}
+	if value, err := engine.Eval("done.receive()"); err != nil || value != 199990000.0 {
+		t.Errorf("task returned %v, %v", value, err)
+	}
+}
+
+func TestEngineOutputFromTasks(t *testing.T) {
+	engine := lox.NewEngine()
+	var output bytes.Buffer
+	engine.SetOutput(&output)
+
+	err := engine.Run(`
+fun chatter(name) {
+  for (var n = 0; n < 1000; n = n + 1) print name;
+}
+var tasks = [spawn chatter("a"), spawn chatter("b"), spawn chatter("c")];
+for (var task in tasks) task.join();
+`)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	counts := make(map[string]int)
+	for _, line := range strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n") {
+		counts[line]++
+	}
+	if want := map[string]int{"a": 1000, "b": 1000, "c": 1000}; fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("printed lines %v, want %v", counts, want)
+	}
+}
+
+func TestEngineCloseEndsSuspendedFibers(t *testing.T) {
+	engine := lox.NewEngine()
+	must(t, engine.Run(`
+fun wait() { Fiber.yield(); }
+for (var i = 0; i < 100; i = i + 1) Fiber(wait).resume();
+`))
+	suspended := runtime.NumGoroutine()
+
+	engine.Close()
+	for try := 0; try < 100 && runtime.NumGoroutine() > suspended-100; try++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if ended := suspended - runtime.NumGoroutine(); ended < 100 {
+		t.Errorf("%d goroutines ended after Close, want the 100 of the suspended fibers", ended)
+	}
+}
+
+func TestEngineTaskPanic(t *testing.T) {
+	engine := lox.NewEngine()
+	must(t, engine.RegisterFunc("divide", func(a, b int) int { return a / b }))
+
+	err := engine.Run(`
+var task = spawn divide(1, 0);
+task.join();
+`)
+	if err == nil || !strings.Contains(err.Error(), "Task panicked: runtime error: integer divide by zero.") {
+		t.Errorf("got %v, want the task's panic as an error", err)
+	}
+	if value, err := engine.Eval("divide(6, 3)"); err != nil || value != 2.0 {
+		t.Errorf("Eval after a task panicked returned %v, %v", value, err)
+	}
+}
+
+func TestEngineRecoversPanicsInCallbacks(t *testing.T) {
+	tests := map[string]string{
+		"fiber": `Fiber(boom).resume();`,
+		"timer": `setTimeout(boom, 0);`,
+		"async": `async fun f() { boom(); } f();`,
+		"then":  `fun f(value) { boom(); } Promise.resolve(1).then(f);`,
+	}
+	for name, source := range tests {
+		t.Run(name, func(t *testing.T) {
+			engine := lox.NewEngine()
+			defer engine.Close()
+			must(t, engine.RegisterFunc("boom", func() { panic("boom") }))
+			if err := engine.Run(source); err == nil || !strings.Contains(err.Error(), "panicked: boom.") {
+				t.Errorf("got %v, want an error for the panic", err)
+			}
+		})
+	}
+}
+
+func TestEngineConversions(t *testing.T) {
+	engine := lox.NewEngine()
+	must(t, engine.RegisterFunc("square", func(n int) int { return n * n }))
+	must(t, engine.RegisterFunc("small", func(n uint8) uint8 { return n }))
+	must(t, engine.RegisterFunc("anything", func(v interface{}) interface{} { return v }))
+	must(t, engine.RegisterFunc("tags", func() interface{} { return map[string]bool{"a": true} }))
+	must(t, engine.RegisterFunc("infinity", func() float64 { return math.Inf(1) }))
+
+	huge := "10000000000 * 10000000000 * 10000000000"
+	for _, source := range []string{
+		"square(" + huge + ")", "square(-" + huge + ")", "square(infinity())", "square(-infinity())",
+		"small(256)", "small(-1)", "small(infinity())",
+	} {
+		if _, err := engine.Eval(source); err == nil || !strings.Contains(err.Error(), "Expected an integer") {
+			t.Errorf("%s: got %v, want a conversion error", source, err)
+		}
+	}
+	if _, err := engine.Eval("tags()"); err == nil || !strings.Contains(err.Error(), "Can't use a Go map[string]bool in Lox.") {
+		t.Errorf("got %v, want an error for the map result", err)
+	}
+	if err := engine.RegisterFunc("counts", func() map[string]int { return nil }); err == nil {
+		t.Errorf("RegisterFunc accepted a map result")
+	}
+	if err := engine.RegisterFunc("pointer", func() *int { return nil }); err == nil {
+		t.Errorf("RegisterFunc accepted a pointer to an int")
+	}
+
+	// Go values passed in are converted too.
+	if value, err := engine.Call("square", 4); err != nil || value != 16.0 {
+		t.Errorf("Call returned %v, %v; want 16", value, err)
+	}
+	must(t, engine.SetGlobal("answer", int64(42)))
+	if value, err := engine.Eval("answer + 0.5"); err != nil || value != 42.5 {
+		t.Errorf("Eval returned %v, %v; want 42.5", value, err)
+	}
+	if err := engine.SetGlobal("table", map[string]int{}); err == nil {
+		t.Errorf("SetGlobal accepted a map")
+	}
+	v := &vec{X: 1}
+	must(t, engine.SetGlobal("v", v))
+	if value, err := engine.Eval("anything(v) == v"); err != nil || value != true {
+		t.Errorf("Eval returned %v, %v; want the same instance back", value, err)
+	}
+}
+
+type vec struct {
+	X, Y float64
+}
+
+type rect struct {
+	Min, Max vec
+}
+
+func (r *rect) Self() *rect {
+	return r
+}
+
+func (r *rect) Width() float64 {
+	return r.Max.X - r.Min.X
+}
+
+func TestEngineGoTypesAreClasses(t *testing.T) {
+	engine := lox.NewEngine()
+	var output bytes.Buffer
+	engine.SetOutput(&output)
+	must(t, engine.RegisterType("Vec", vec{}))
+	must(t, engine.RegisterType("Rect", rect{}))
+
+	err := engine.Run(`
+var r = Rect(Vec(0, 0), Vec(4, 3));
+r.min.x = 1;
+print r.min.x;
+print r.width();
+print r.self() == r;
+print r.min == r.min;
+print isInstance(r, Rect);
+print type(r) == Rect;
+print fields(r);
+
+class Square < Rect {
+  init(side) {
+    super.init(Vec(0, 0), Vec(side, side));
+    this.label = "square";
+  }
+
+  area() { return this.width() * this.width(); }
+}
+
+var s = Square(2);
+print s.area();
+print isInstance(s, Rect);
+print s.self() == s;
+print s.label;
+print Set(r, r.self(), s.self()).len();
+`)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	want := "1\n3\ntrue\ntrue\ntrue\ntrue\n[max, min]\n4\ntrue\ntrue\nsquare\n2\n"
+	if output.String() != want {
+		t.Errorf("printed %q, want %q", output.String(), want)
+	}
+
+	err = engine.Run(`
+class Broken < Rect {
+  init() {}
+}
+Broken().width();
+`)
+	if err == nil || !strings.Contains(err.Error(), "Broken instance has no Go value; its init must call super.init().") {
+		t.Errorf("got %v, want an error for the missing super.init()", err)
+	}
+}
+
+func TestEngineLetsGoValuesBeCollected(t *testing.T) {
+	engine := lox.NewEngine()
+	var collected atomic.Int32
+	must(t, engine.RegisterFunc("point", func() *vec {
+		v := &vec{X: 1}
+		runtime.SetFinalizer(v, func(*vec) { collected.Add(1) })
+		return v
+	}))
+	must(t, engine.Run(`for (var i = 0; i < 100; i = i + 1) point().x;`))
+
+	for try := 0; try < 100 && collected.Load() == 0; try++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if collected.Load() == 0 {
+		t.Error("no struct Go returned to Lox was collected")
+	}
+	runtime.KeepAlive(engine)
+}
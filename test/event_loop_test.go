@@ -0,0 +1,133 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/chase-compton/LOX_GO/errors"
+	"github.com/chase-compton/LOX_GO/interpreter"
+	"github.com/chase-compton/LOX_GO/parser"
+	"github.com/chase-compton/LOX_GO/resolver"
+	"github.com/chase-compton/LOX_GO/scanner"
+)
+
+// runOnManualClock runs source on an interpreter whose timers are driven by
+// a ManualClock, leaving its timers and callbacks pending. It returns the
+// interpreter, the clock and what has been printed so far.
+func runOnManualClock(t *testing.T, source string) (*interpreter.Interpreter, *interpreter.ManualClock, *bytes.Buffer) {
+	t.Helper()
+	diagnostics := errors.NewCollector()
+	interp := interpreter.NewInterpreter(diagnostics)
+	clock := interpreter.NewManualClock()
+	interp.SetClock(clock)
+	var output bytes.Buffer
+	interp.SetOutput(&output)
+
+	tokens := scanner.NewScanner(source, diagnostics).ScanTokens()
+	statements, _ := parser.NewParser(tokens, diagnostics).Parse()
+	if !diagnostics.HasErrors() {
+		_ = resolver.NewResolver(interp, diagnostics).Resolve(statements)
+	}
+	if diagnostics.HasErrors() {
+		t.Fatalf("errors in test source: %v", diagnostics.Diagnostics())
+	}
+	if err := interp.Interpret(statements); err != nil {
+		t.Fatalf("Interpret failed: %v", err)
+	}
+	return interp, clock, &output
+}
+
+// advance moves clock on by d and runs the callbacks that are then due,
+// returning what they printed.
+func advance(t *testing.T, interp *interpreter.Interpreter, clock *interpreter.ManualClock, output *bytes.Buffer, d time.Duration) string {
+	t.Helper()
+	output.Reset()
+	clock.Advance(d)
+	if err := interp.RunDueEvents(); err != nil {
+		t.Fatalf("RunDueEvents failed: %v", err)
+	}
+	return output.String()
+}
+
+func TestManualClockFiresTimersInOrder(t *testing.T) {
+	interp, clock, output := runOnManualClock(t, `
+fun say(message) {
+  fun callback() { print message; }
+  return callback;
+}
+setTimeout(say("300"), 300);
+setTimeout(say("100"), 100);
+setTimeout(say("100 again"), 100);
+var ticks = 0;
+var id;
+fun tick() {
+  ticks = ticks + 1;
+  print ticks;
+  if (ticks == 2) clearInterval(id);
+}
+id = setInterval(tick, 150);
+`)
+
+	steps := []struct {
+		by   time.Duration
+		want string
+	}{
+		{0, ""},
+		{99 * time.Millisecond, ""},
+		{1 * time.Millisecond, "100\n100 again\n"},
+		{50 * time.Millisecond, "1\n"},
+		{150 * time.Millisecond, "300\n2\n"},
+		{time.Second, ""},
+	}
+	for _, step := range steps {
+		got := advance(t, interp, clock, output, step.by)
+		if got != step.want {
+			t.Fatalf("at %v printed %q, want %q", clock.Now(), got, step.want)
+		}
+	}
+}
+
+func TestManualClockSettlesPromises(t *testing.T) {
+	interp, clock, output := runOnManualClock(t, `
+fun executor(resolve, reject) {
+  fun fire() { resolve("done"); }
+  setTimeout(fire, 50);
+}
+fun report(value) { print "settled " + value; }
+fun failed(reason) { print "rejected " + reason; }
+Promise(executor).then(report);
+Promise.reject("early").catch(failed);
+print "waiting";
+`)
+	if output.String() != "waiting\n" {
+		t.Fatalf("script printed %q, want %q", output.String(), "waiting\n")
+	}
+
+	// Reactions to settled promises run without the clock moving.
+	if got := advance(t, interp, clock, output, 0); got != "rejected early\n" {
+		t.Errorf("first run printed %q, want the rejection", got)
+	}
+	if got := advance(t, interp, clock, output, 49*time.Millisecond); got != "" {
+		t.Errorf("printed %q before the promise settled", got)
+	}
+	if got := advance(t, interp, clock, output, time.Millisecond); got != "settled done\n" {
+		t.Errorf("printed %q when the promise settled, want %q", got, "settled done\n")
+	}
+}
+
+func TestManualClockDrainsWithoutWaiting(t *testing.T) {
+	interp, clock, output := runOnManualClock(t, `
+fun late() { print "late"; }
+setTimeout(late, 60000);
+`)
+	if err := interp.RunEventLoop(); err != nil {
+		t.Fatalf("RunEventLoop failed: %v", err)
+	}
+	if output.String() != "late\n" {
+		t.Errorf("printed %q, want %q", output.String(), "late\n")
+	}
+	if clock.Now() != time.Minute {
+		t.Errorf("clock is at %v after draining, want %v", clock.Now(), time.Minute)
+	}
+}
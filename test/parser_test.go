@@ -0,0 +1,87 @@
+package test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/chase-compton/LOX_GO/errors"
+	"github.com/chase-compton/LOX_GO/parser"
+	"github.com/chase-compton/LOX_GO/scanner"
+)
+
+// syntaxErrors parses source and returns the errors reported.
+func syntaxErrors(source string) []string {
+	diagnostics := errors.NewCollector()
+	tokens := scanner.NewScanner(source, diagnostics).ScanTokens()
+	_, _ = parser.NewParser(tokens, diagnostics).Parse()
+
+	var reported []string
+	for _, d := range diagnostics.Diagnostics() {
+		reported = append(reported, fmt.Sprintf("%d: %s", d.Line, d.Message))
+	}
+	return reported
+}
+
+func TestSyntaxErrorRecovery(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{
+			name: "function literal",
+			source: `Fiber(fun() {
+  print 1;
+});
+print 2;`,
+			want: []string{"1: Expect expression."},
+		},
+		{
+			name: "match expression",
+			source: `print (1 +) + match (1) { case 1 => 2; } + 3;
+print 2`,
+			want: []string{"1: Expect expression.", "2: Expect ';' after value."},
+		},
+		{
+			name: "before match",
+			source: `var a = 1
+match (a) {
+  case 1 => print 2
+}`,
+			want: []string{"2: Expect ';' after variable declaration.", "4: Expect ';' after value."},
+		},
+		{
+			name: "before select",
+			source: `print 1
+select {
+  default => print 2
+}`,
+			want: []string{"2: Expect ';' after value.", "4: Expect ';' after value."},
+		},
+		{
+			name: "before async",
+			source: `print 1
+async fun f() {
+  print 2
+}`,
+			want: []string{"2: Expect ';' after value.", "4: Expect ';' after value."},
+		},
+		{
+			name: "before decorator",
+			source: `print 1
+@memo
+fun f() {
+  print 2
+}`,
+			want: []string{"2: Expect ';' after value.", "5: Expect ';' after value."},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := syntaxErrors(test.source); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("reported %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chase-compton/LOX_GO/errors"
+	"github.com/chase-compton/LOX_GO/interpreter"
+)
+
+// goCallback is a Callable an embedder might define, which, being a func,
+// can't be compared.
+type goCallback func()
+
+func (f goCallback) Arity() int {
+	return 0
+}
+
+func (f goCallback) Call(*interpreter.Interpreter, []interface{}) (interface{}, error) {
+	f()
+	return nil, nil
+}
+
+func (f goCallback) String() string {
+	return "<native fn>"
+}
+
+func TestSetRejectsUncomparableValues(t *testing.T) {
+	interp := interpreter.NewInterpreter(errors.NewCollector())
+	set, _ := interp.LookupGlobal("Set")
+	callback := goCallback(func() {})
+
+	for _, element := range []interface{}{callback, interpreter.NewLoxTuple([]interface{}{1.0, callback})} {
+		_, err := interp.CallValue(set, []interface{}{element})
+		if err == nil || !strings.Contains(err.Error(), "can't be compared") {
+			t.Errorf("Set(%v) gave %v, want an error", element, err)
+		}
+	}
+}
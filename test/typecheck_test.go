@@ -0,0 +1,177 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chase-compton/LOX_GO/errors"
+	"github.com/chase-compton/LOX_GO/parser"
+	"github.com/chase-compton/LOX_GO/scanner"
+	"github.com/chase-compton/LOX_GO/typecheck"
+)
+
+func typeErrors(t *testing.T, source string) []string {
+	t.Helper()
+	return analyze(t, source, nil, false, true)
+}
+
+func TestTypeCheckReportsMismatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{
+			name:   "variable",
+			source: `var count: num = "zero";`,
+			want:   []string{"1: E0600 Can't assign str to variable 'count' of type num."},
+		},
+		{
+			name: "argument",
+			source: `fun square(n: num): num { return n * n; }
+square("two");`,
+			want: []string{"2: E0600 Argument 1 to 'square' must be num, not str."},
+		},
+		{
+			name: "every argument",
+			source: `fun area(w: num, h: num): num { return w * h; }
+area("1", "2");`,
+			want: []string{
+				"2: E0600 Argument 1 to 'area' must be num, not str.",
+				"2: E0600 Argument 2 to 'area' must be num, not str.",
+			},
+		},
+		{
+			name: "return",
+			source: `fun name(): str {
+  return 42;
+}`,
+			want: []string{"2: E0600 Can't return num from 'name', which returns str."},
+		},
+		{
+			name: "field",
+			source: `class Box { size: num; }
+var box = Box();
+box.size = "large";`,
+			want: []string{"3: E0600 Can't assign str to field 'size' of type num."},
+		},
+		{
+			name:   "unknown type",
+			source: `var x: Widget = nil;`,
+			want:   []string{"1: E0601 Unknown type 'Widget'."},
+		},
+		{
+			name: "nil not narrowed",
+			source: `fun length(s: str | nil): num {
+  return s + 1;
+}`,
+			want: []string{"2: E0600 Operator '+' expects two numbers or two strings, not nil | str and num."},
+		},
+		{
+			name: "missing return",
+			source: `fun f(): num {}
+fun g(n: num): num {
+  if (n > 0) return n;
+}`,
+			want: []string{
+				"1: E0600 'f' can reach the end without returning num.",
+				"2: E0600 'g' can reach the end without returning num.",
+			},
+		},
+		{
+			name: "narrowing undone by assignment",
+			source: `fun f(m: num | nil): num {
+  if (m == nil) return 0;
+  m = nil;
+  return m;
+}`,
+			want: []string{"4: E0600 Can't return nil from 'f', which returns num."},
+		},
+		{
+			name: "narrowing undone in loop",
+			source: `fun f(m: num | nil) {
+  while (true) {
+    if (m != nil) print m + 1;
+    var n: num = m;
+    m = nil;
+  }
+}`,
+			want: []string{"4: E0600 Can't assign nil | num to variable 'n' of type num."},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := typeErrors(t, test.source); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("reported %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTypeCheckUnderlinesArgument(t *testing.T) {
+	source := `fun area(w: num, h: num): num { return w * h; }
+print area(1, "2" + "3");`
+	diagnostics := errors.NewCollector()
+	tokens := scanner.NewScanner(source, diagnostics).ScanTokens()
+	statements, _ := parser.NewParser(tokens, diagnostics).Parse()
+	_ = typecheck.NewChecker(diagnostics).Check(statements)
+
+	reported := diagnostics.Diagnostics()
+	if len(reported) != 1 {
+		t.Fatalf("reported %v, want one error", reported)
+	}
+	want := errors.Span{Line: 2, Column: 15, Length: 9}
+	if reported[0].Span != want {
+		t.Errorf("underlined %+v, want %+v", reported[0].Span, want)
+	}
+}
+
+func TestTypeCheckAcceptsValidPrograms(t *testing.T) {
+	tests := map[string]string{
+		"and": `fun f(m: num | nil): num {
+  if (m != nil and m > 0) return m;
+  return 0;
+}`,
+		"or": `fun f(m: num | nil): num {
+  if (m == nil or m < 0) return 0;
+  return m;
+}`,
+		"not": `fun f(m: num | nil): num {
+  if (!m) return 0;
+  return m;
+}`,
+		"assignment in branch": `fun f(m: num | nil): num {
+  if (m == nil) { m = 0; }
+  return m;
+}`,
+		"assignment in both branches": `fun f(m: num | nil, flag: bool): num {
+  if (flag) m = 1; else m = 2;
+  return m;
+}`,
+		"while": `fun f(m: num | nil): num {
+  while (m == nil) m = 1;
+  return m;
+}`,
+		"else branch": `fun f(m: str | nil): str {
+  if (m != nil) return m; else return "none";
+}`,
+		"guarded right operand": `fun f(m: num | nil): bool {
+  return m != nil and m + 1 > 2;
+}`,
+		"infinite loop": `fun f(): num {
+  while (true) { return 1; }
+}`,
+		"nil return type": `fun f(): num | nil {}`,
+		"unannotated": `fun f(m) {
+  if (m == nil) m = 0;
+  return m + 1;
+}`,
+	}
+	for name, source := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := typeErrors(t, source); len(got) > 0 {
+				t.Errorf("reported %q for a valid program", got)
+			}
+		})
+	}
+}
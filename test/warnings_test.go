@@ -0,0 +1,236 @@
+package test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/chase-compton/LOX_GO/errors"
+	"github.com/chase-compton/LOX_GO/interpreter"
+	"github.com/chase-compton/LOX_GO/parser"
+	"github.com/chase-compton/LOX_GO/resolver"
+	"github.com/chase-compton/LOX_GO/scanner"
+	"github.com/chase-compton/LOX_GO/typecheck"
+)
+
+// analyze scans, parses and resolves source, calling configure on the
+// resolver before it runs. As `lox check` does, it then runs the resolver's
+// checks if check is set, and type checks it if typeCheck is set. It
+// returns what was reported, each as "line: CODE message", followed by
+// " (help)" for diagnostics with help.
+func analyze(t *testing.T, source string, configure func(*resolver.Resolver), check, typeCheck bool) []string {
+	t.Helper()
+	diagnostics := errors.NewCollector()
+	lexer := scanner.NewScanner(source, diagnostics)
+	tokens := lexer.ScanTokens()
+	statements, _ := parser.NewParser(tokens, diagnostics).Parse()
+	if diagnostics.HasErrors() {
+		t.Fatalf("syntax errors in test source: %v", diagnostics.Diagnostics())
+	}
+
+	res := resolver.NewResolver(interpreter.NewInterpreter(diagnostics), diagnostics)
+	res.Suppressions = lexer.Suppressions()
+	if configure != nil {
+		configure(res)
+	}
+	_ = res.Resolve(statements)
+	if check && !diagnostics.HasErrors() {
+		_ = res.Check()
+	}
+	if typeCheck && !diagnostics.HasErrors() {
+		_ = typecheck.NewChecker(diagnostics).Check(statements)
+	}
+
+	var reported []string
+	for _, d := range diagnostics.Diagnostics() {
+		line := fmt.Sprintf("%d: %s %s", d.Line, d.Code, d.Message)
+		if d.Help != "" {
+			line += " (" + d.Help + ")"
+		}
+		reported = append(reported, line)
+	}
+	return reported
+}
+
+// warnings resolves source with every warning enabled and returns what was
+// reported.
+func warnings(t *testing.T, source string) []string {
+	t.Helper()
+	return analyze(t, source, func(res *resolver.Resolver) {
+		for _, code := range errors.WarningCodes {
+			res.EnableWarning(code)
+		}
+	}, false, false)
+}
+
+func TestWarnings(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{
+			name: "non-exhaustive match",
+			source: `class Shape {}
+class Circle < Shape {}
+class Square < Shape {}
+fun name(shape) {
+  match (shape) {
+    case Circle() => return "circle";
+  }
+}
+print name;`,
+			want: []string{"5: W0001 Match over 'Shape' is not exhaustive; missing 'Square'."},
+		},
+		{
+			name: "unused variable",
+			source: `fun f() {
+  var count = 1;
+}
+f();`,
+			want: []string{"2: W0002 Local variable 'count' is never used."},
+		},
+		{
+			name:   "unused parameter",
+			source: `fun f(a, b) { return a; } print f;`,
+			want:   []string{"1: W0003 Parameter 'b' is never used."},
+		},
+		{
+			name: "unreachable code",
+			source: `fun f() {
+  return 1;
+  print "never";
+}
+f();`,
+			want: []string{"3: W0004 Unreachable code."},
+		},
+		{
+			name: "shadowed variable",
+			source: `var total = 0;
+fun f() {
+  var total = 1;
+  return total;
+}
+f();`,
+			want: []string{"3: W0005 Local 'total' shadows an outer variable."},
+		},
+		{
+			name:   "constant condition",
+			source: `if (false) print "never";`,
+			want:   []string{"1: W0006 Condition is always false."},
+		},
+		{
+			name: "dead assignment",
+			source: `fun f() {
+  var n = 1;
+  print n;
+  n = 2;
+}
+f();`,
+			want: []string{"4: W0007 Value assigned to 'n' is never read."},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := warnings(t, test.source); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("reported %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWarningsAreOffByDefault(t *testing.T) {
+	source := `fun f(unused) {
+  var never = 1;
+  if (true) return 1;
+}
+f(1);`
+	if got := analyze(t, source, nil, false, false); len(got) > 0 {
+		t.Errorf("reported %q with no warnings enabled", got)
+	}
+}
+
+func TestIgnoreComments(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{
+			name: "trailing",
+			source: `fun f(unused) { // lox:ignore W0003
+  return 1;
+}
+print f;`,
+		},
+		{
+			name: "on the line before",
+			source: `fun f() {
+  // lox:ignore W0002
+  var x = 1;
+}
+f();`,
+		},
+		{
+			name: "every code",
+			source: `fun f(a) {
+  // lox:ignore
+  var total = 1;
+}
+var total = 0;
+f(1); // lox:ignore`,
+			want: []string{"1: W0003 Parameter 'a' is never used."},
+		},
+		{
+			name: "only the named code",
+			source: `var x = 0;
+fun f() {
+  var x = 1; // lox:ignore W0005
+}
+f();`,
+			want: []string{"3: W0002 Local variable 'x' is never used."},
+		},
+		{
+			name: "several codes",
+			source: `var x = 0;
+fun f() {
+  var x = 1; // lox:ignore W0002, W0005
+}
+f();`,
+		},
+		{
+			name: "trailing doesn't cover the next line",
+			source: `fun f() {
+  var z = 1; // lox:ignore W0002
+  var q = 2;
+}
+f();`,
+			want: []string{"3: W0002 Local variable 'q' is never used."},
+		},
+		{
+			name: "doesn't cover two lines on",
+			source: `fun f() {
+  // lox:ignore W0002
+  var z = 1;
+  var q = 2;
+}
+f();`,
+			want: []string{"4: W0002 Local variable 'q' is never used."},
+		},
+		{
+			name: "errors aren't silenced",
+			source: `fun f() {
+  return 1;
+}
+return 2; // lox:ignore`,
+			want: []string{"4: E0300 Can't return from top-level code."},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := warnings(t, test.source); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("reported %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,1066 @@
+package typecheck
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chase-compton/LOX_GO/ast"
+	"github.com/chase-compton/LOX_GO/errors"
+	"github.com/chase-compton/LOX_GO/scanner"
+)
+
+// Checker checks a program's type annotations before it runs. It infers
+// the types of locals and expressions, and reports operands, arguments,
+// returns and assignments that can't match the annotated types.
+type Checker struct {
+	diagnostics errors.Diagnostics
+	errors      TypeErrors
+	// reporting is off during the first pass, which only finds the
+	// variables that are ever reassigned.
+	reporting  bool
+	reassigned map[int]bool
+	// reported holds the offsets of errors already reported, since an
+	// annotation is read again at every call it applies to.
+	reported map[int]bool
+
+	scopes   []map[string]*variable
+	classes  map[string]*class
+	function *function
+	class    *class
+}
+
+// variable is what the checker knows about a name in scope.
+type variable struct {
+	// key identifies the declaration, so narrowed copies of a variable
+	// are recognized as the same variable.
+	key int
+	// declared is the annotated type, and annotated whether there was
+	// one. current is the type known at this point in the code, which is
+	// narrower than declared after a nil check.
+	declared  Type
+	annotated bool
+	current   Type
+	// function and class are set for names declared by `fun` and `class`,
+	// so calls through them can be checked.
+	function *ast.FunctionStmt
+	class    *class
+}
+
+type class struct {
+	name       string
+	superclass *class
+	fields     map[string]Type
+	methods    map[string]*ast.FunctionStmt
+}
+
+// function is the function whose body is being checked.
+type function struct {
+	name       string
+	returnType Type
+	annotated  bool
+}
+
+func NewChecker(diagnostics errors.Diagnostics) *Checker {
+	return &Checker{
+		diagnostics: diagnostics,
+		reassigned:  make(map[int]bool),
+		reported:    make(map[int]bool),
+		classes:     make(map[string]*class),
+	}
+}
+
+// Check checks a resolved program, returning a TypeErrors listing every
+// mismatch found.
+func (c *Checker) Check(statements []ast.Stmt) error {
+	for _, reporting := range []bool{false, true} {
+		c.reporting = reporting
+		c.scopes = []map[string]*variable{make(map[string]*variable)}
+		c.hoist(statements)
+		c.checkStatements(statements)
+	}
+	if len(c.errors) > 0 {
+		return c.errors
+	}
+	return nil
+}
+
+// TypeError is a type mismatch at a token.
+type TypeError struct {
+	Token   scanner.Token
+	Message string
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("[line %d] Error at '%s': %s", e.Token.Line, e.Token.Lexeme, e.Message)
+}
+
+// TypeErrors lists every type error in a program, in the order found.
+type TypeErrors []*TypeError
+
+func (e TypeErrors) Error() string {
+	messages := make([]string, len(e))
+	for n, err := range e {
+		messages[n] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (c *Checker) error(token scanner.Token, code, message string) {
+	c.report(token, token.Start(), token.Diagnostic(code, message))
+}
+
+// errorIn reports a type error underlining expr. The TypeError is at token,
+// since expr may not be a single token.
+func (c *Checker) errorIn(expr ast.Expr, token scanner.Token, code, message string) {
+	span := expr.SourceSpan()
+	c.report(token, span.Start, span.Diagnostic(code, message))
+}
+
+func (c *Checker) report(token scanner.Token, at scanner.Position, diagnostic errors.Diagnostic) {
+	if !c.reporting || c.reported[at.Offset] {
+		return
+	}
+	c.reported[at.Offset] = true
+	c.errors = append(c.errors, &TypeError{Token: token, Message: diagnostic.Message})
+	c.diagnostics.Report(diagnostic)
+}
+
+// hoist declares the functions and classes of a block up front, since they
+// can be called from code written before them.
+func (c *Checker) hoist(statements []ast.Stmt) {
+	for _, stmt := range statements {
+		switch s := stmt.(type) {
+		case *ast.FunctionStmt:
+			c.declare(s.Name, &variable{function: s})
+		case *ast.ClassStmt:
+			c.declare(s.Name, &variable{class: c.declareClass(s)})
+		}
+	}
+}
+
+func (c *Checker) declareClass(stmt *ast.ClassStmt) *class {
+	if existing, ok := c.classes[stmt.Name.Lexeme]; ok {
+		return existing
+	}
+	cls := &class{
+		name:    stmt.Name.Lexeme,
+		fields:  make(map[string]Type),
+		methods: make(map[string]*ast.FunctionStmt),
+	}
+	c.classes[cls.name] = cls
+	return cls
+}
+
+func (c *Checker) beginScope() {
+	c.scopes = append(c.scopes, make(map[string]*variable))
+}
+
+func (c *Checker) endScope() {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+func (c *Checker) declare(name scanner.Token, v *variable) *variable {
+	v.key = name.Offset
+	c.scopes[len(c.scopes)-1][name.Lexeme] = v
+	return v
+}
+
+func (c *Checker) lookup(name string) *variable {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if v, ok := c.scopes[i][name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// typeOf reads an annotation. A missing annotation, 'any', or a union
+// including 'any' gives the unknown type.
+func (c *Checker) typeOf(annotation *ast.TypeAnnotation) Type {
+	if annotation == nil {
+		return Type{}
+	}
+	var names []string
+	for _, name := range annotation.Alternatives {
+		switch {
+		case name.Lexeme == anyType:
+			return Type{}
+		case builtinTypes[name.Lexeme] || c.classes[name.Lexeme] != nil:
+			names = append(names, name.Lexeme)
+		default:
+			c.error(name, errors.CodeUnknownType, fmt.Sprintf("Unknown type '%s'.", name.Lexeme))
+			return Type{}
+		}
+	}
+	return newType(true, names...)
+}
+
+// assignable reports whether every value of type from is also a value of
+// type to. Unknown types are assignable either way.
+func (c *Checker) assignable(from, to Type) bool {
+	if from.unknown() || to.unknown() {
+		return true
+	}
+	for _, f := range from.names {
+		ok := false
+		for _, t := range to.names {
+			if f == t || c.isSubclass(f, t) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Checker) isSubclass(name, ancestor string) bool {
+	cls := c.classes[name]
+	if cls == nil {
+		return false
+	}
+	for super := cls.superclass; super != nil; super = super.superclass {
+		if super.name == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// check evaluates the type of an expression.
+func (c *Checker) check(expr ast.Expr) Type {
+	t, _ := expr.Accept(c)
+	if t == nil {
+		return Type{}
+	}
+	return t.(Type)
+}
+
+func (c *Checker) checkStmt(stmt ast.Stmt) {
+	stmt.Accept(c)
+}
+
+func (c *Checker) checkStatements(statements []ast.Stmt) {
+	for _, stmt := range statements {
+		c.checkStmt(stmt)
+	}
+}
+
+func (c *Checker) checkBlock(statements []ast.Stmt) {
+	c.beginScope()
+	c.hoist(statements)
+	c.checkStatements(statements)
+	c.restore(c.endBranch())
+}
+
+// checkAssignment reports value, of type from, not fitting a target of
+// type to. what describes the target.
+func (c *Checker) checkAssignment(token scanner.Token, from, to Type, what string) {
+	if !c.assignable(from, to) {
+		c.error(token, errors.CodeTypeError, fmt.Sprintf("Can't assign %s to %s of type %s.", from, what, to))
+	}
+}
+
+// checkArguments checks the arguments of call, of the given types, against
+// the annotated parameter types of the function called, returning its
+// annotated return type.
+func (c *Checker) checkArguments(call *ast.Call, callee *ast.FunctionStmt, arguments []Type) Type {
+	for n, argument := range arguments {
+		if n >= len(callee.ParamTypes) {
+			break
+		}
+		param := c.typeOf(callee.ParamTypes[n])
+		if !c.assignable(argument, param) {
+			c.errorIn(call.Arguments[n], call.Paren, errors.CodeTypeError, fmt.Sprintf(
+				"Argument %d to '%s' must be %s, not %s.", n+1, callee.Name.Lexeme, param, argument))
+		}
+	}
+	if callee.IsAsync {
+		// Calling an async function gives a future, not its result.
+		return Type{}
+	}
+	return c.typeOf(callee.ReturnType)
+}
+
+func (cls *class) findMethod(name string) *ast.FunctionStmt {
+	for k := cls; k != nil; k = k.superclass {
+		if method, ok := k.methods[name]; ok {
+			return method
+		}
+	}
+	return nil
+}
+
+func (cls *class) findField(name string) (Type, bool) {
+	for k := cls; k != nil; k = k.superclass {
+		if field, ok := k.fields[name]; ok {
+			return field, true
+		}
+	}
+	return Type{}, false
+}
+
+// classOf returns the class of an expression whose type is a single class.
+func (c *Checker) classOf(t Type) *class {
+	if len(t.names) != 1 {
+		return nil
+	}
+	return c.classes[t.names[0]]
+}
+
+func (c *Checker) VisitExpressionStmt(stmt *ast.ExpressionStmt) (interface{}, error) {
+	c.check(stmt.Expression)
+	return nil, nil
+}
+
+func (c *Checker) VisitPrintStmt(stmt *ast.PrintStmt) (interface{}, error) {
+	c.check(stmt.Expression)
+	return nil, nil
+}
+
+func (c *Checker) VisitAssertStmt(stmt *ast.AssertStmt) (interface{}, error) {
+	c.checkAssertion(stmt.Assertion)
+	return nil, nil
+}
+
+func (c *Checker) checkAssertion(assertion *ast.Assertion) {
+	c.check(assertion.Condition)
+	if assertion.Message != nil {
+		c.check(assertion.Message)
+	}
+}
+
+func (c *Checker) VisitVarStmt(stmt *ast.VarStmt) (interface{}, error) {
+	declared := c.typeOf(stmt.Type)
+	value := newType(false, nilType)
+	if stmt.Initializer != nil {
+		value = c.check(stmt.Initializer)
+		if stmt.Type != nil {
+			c.checkAssignment(stmt.Name, value, declared, fmt.Sprintf("variable '%s'", stmt.Name.Lexeme))
+		}
+	}
+
+	v := &variable{declared: declared, annotated: stmt.Type != nil}
+	switch {
+	case v.annotated:
+		v.current = declared
+	case !c.reassigned[stmt.Name.Offset]:
+		// An unannotated variable that never changes keeps the type it
+		// started with.
+		v.current = value
+	}
+	c.declare(stmt.Name, v)
+	return nil, nil
+}
+
+func (c *Checker) VisitBlockStmt(stmt *ast.BlockStmt) (interface{}, error) {
+	c.checkBlock(stmt.Statements)
+	return nil, nil
+}
+
+func (c *Checker) VisitIfStmt(stmt *ast.IfStmt) (interface{}, error) {
+	c.check(stmt.Condition)
+	var paths []map[string]*variable
+	then := c.branch(c.facts(stmt.Condition, true), func() { c.checkStmt(stmt.ThenBranch) })
+	if !exits(stmt.ThenBranch) {
+		paths = append(paths, then)
+	}
+	otherwise := c.branch(c.facts(stmt.Condition, false), func() {
+		if stmt.ElseBranch != nil {
+			c.checkStmt(stmt.ElseBranch)
+		}
+	})
+	// After `if (x == nil) return;`, x isn't nil for the rest of the block.
+	if stmt.ElseBranch == nil || !exits(stmt.ElseBranch) {
+		paths = append(paths, otherwise)
+	}
+	c.join(paths...)
+	return nil, nil
+}
+
+// branch checks code that only runs on some paths through the program, in
+// a scope where variables have the types in facts. It returns the variables
+// the branch leaves narrowed, to be joined with the other paths.
+func (c *Checker) branch(facts map[string]Type, check func()) map[string]*variable {
+	c.beginScope()
+	c.apply(facts)
+	check()
+	return c.endBranch()
+}
+
+// endBranch ends a scope, returning the variables of enclosing scopes that
+// it narrowed.
+func (c *Checker) endBranch() map[string]*variable {
+	scope := c.scopes[len(c.scopes)-1]
+	c.endScope()
+	narrowed := make(map[string]*variable)
+	for name, v := range scope {
+		if outer := c.lookup(name); outer != nil && outer.key == v.key {
+			narrowed[name] = v
+		}
+	}
+	return narrowed
+}
+
+// restore carries the variables narrowed by a block that always runs to the
+// end into the enclosing scope.
+func (c *Checker) restore(narrowed map[string]*variable) {
+	for name, v := range narrowed {
+		c.scopes[len(c.scopes)-1][name] = v
+	}
+}
+
+// join takes paths, the branches that can reach this point, and narrows
+// each variable narrowed on any of them to the union of its types on all
+// of them. A path that doesn't narrow a variable leaves the type it has
+// here.
+func (c *Checker) join(paths ...map[string]*variable) {
+	names := make(map[string]bool)
+	for _, path := range paths {
+		for name := range path {
+			names[name] = true
+		}
+	}
+	for name := range names {
+		v := c.lookup(name)
+		if v == nil {
+			continue
+		}
+		var joined Type
+		for n, path := range paths {
+			t := v.current
+			if narrowed, ok := path[name]; ok {
+				t = narrowed.current
+			}
+			if n == 0 {
+				joined = t
+			} else {
+				joined = union(joined, t)
+			}
+		}
+		c.narrow(name, joined)
+	}
+}
+
+func (c *Checker) apply(facts map[string]Type) {
+	for name, t := range facts {
+		c.narrow(name, t)
+	}
+}
+
+// narrow records in the innermost scope that the variable name has type t
+// from here on.
+func (c *Checker) narrow(name string, t Type) {
+	v := c.lookup(name)
+	if v == nil {
+		return
+	}
+	narrowed := *v
+	narrowed.current = t
+	c.scopes[len(c.scopes)-1][name] = &narrowed
+}
+
+// facts returns the narrower types that variables are known to have when
+// condition evaluates to truth. It understands `x != nil`, `x == nil`, a
+// variable tested on its own, and '!', 'and' and 'or' applied to those.
+func (c *Checker) facts(condition ast.Expr, truth bool) map[string]Type {
+	switch e := condition.(type) {
+	case *ast.Grouping:
+		return c.facts(e.Expression, truth)
+	case *ast.Unary:
+		if e.Operator.Type == scanner.BANG {
+			return c.facts(e.Right, !truth)
+		}
+	case *ast.Variable:
+		v := c.lookup(e.Name.Lexeme)
+		if v == nil || v.current.unknown() {
+			return nil
+		}
+		t := v.current.without(nilType)
+		if !truth {
+			t = v.current.falsy()
+		}
+		if t.unknown() {
+			return nil
+		}
+		return map[string]Type{e.Name.Lexeme: t}
+	case *ast.Binary:
+		name, nonNilWhenTrue, ok := nilCheck(e)
+		if !ok {
+			return nil
+		}
+		v := c.lookup(name)
+		if v == nil || !v.current.has(nilType) {
+			return nil
+		}
+		t := newType(v.current.annotated, nilType)
+		if nonNilWhenTrue == truth {
+			t = v.current.without(nilType)
+		}
+		if t.unknown() {
+			return nil
+		}
+		return map[string]Type{name: t}
+	case *ast.Logical:
+		left := c.facts(e.Left, truth)
+		if (e.Operator.Type == scanner.AND) == truth {
+			// A true 'and' or a false 'or': both operands had the value.
+			c.beginScope()
+			c.apply(left)
+			right := c.facts(e.Right, truth)
+			c.endScope()
+			both := make(map[string]Type)
+			for name, t := range left {
+				both[name] = t
+			}
+			for name, t := range right {
+				both[name] = t
+			}
+			return both
+		}
+		// Either the left operand had the value, or it didn't and the
+		// right one did.
+		c.beginScope()
+		c.apply(c.facts(e.Left, !truth))
+		right := c.facts(e.Right, truth)
+		c.endScope()
+		either := make(map[string]Type)
+		for name, t := range left {
+			if other, ok := right[name]; ok {
+				either[name] = union(t, other)
+			}
+		}
+		return either
+	}
+	return nil
+}
+
+// nilCheck recognizes `x != nil` and `x == nil`, returning the variable and
+// whether it is non-nil when the condition is true.
+func nilCheck(binary *ast.Binary) (string, bool, bool) {
+	if binary.Operator.Type != scanner.BANG_EQUAL && binary.Operator.Type != scanner.EQUAL_EQUAL {
+		return "", false, false
+	}
+	variable, ok := binary.Left.(*ast.Variable)
+	other := binary.Right
+	if !ok {
+		variable, ok = binary.Right.(*ast.Variable)
+		other = binary.Left
+	}
+	literal, isLiteral := other.(*ast.Literal)
+	if !ok || !isLiteral || literal.Value != nil {
+		return "", false, false
+	}
+	return variable.Name.Lexeme, binary.Operator.Type == scanner.BANG_EQUAL, true
+}
+
+// exits reports whether stmt never finishes normally, because it always
+// returns or loops forever.
+func exits(stmt ast.Stmt) bool {
+	switch s := stmt.(type) {
+	case *ast.ReturnStmt:
+		return true
+	case *ast.BlockStmt:
+		return exitsBlock(s.Statements)
+	case *ast.IfStmt:
+		return s.ElseBranch != nil && exits(s.ThenBranch) && exits(s.ElseBranch)
+	case *ast.WhileStmt:
+		literal, ok := s.Condition.(*ast.Literal)
+		return ok && literal.Value == true
+	}
+	return false
+}
+
+// exitsBlock reports whether a list of statements never finishes normally.
+func exitsBlock(statements []ast.Stmt) bool {
+	for _, stmt := range statements {
+		if exits(stmt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Checker) VisitWhileStmt(stmt *ast.WhileStmt) (interface{}, error) {
+	c.loop(stmt.Condition, func() { c.checkStmt(stmt.Body) })
+	return nil, nil
+}
+
+func (c *Checker) VisitForInStmt(stmt *ast.ForInStmt) (interface{}, error) {
+	c.check(stmt.Iterable)
+	c.loop(nil, func() {
+		c.declare(stmt.Name, &variable{})
+		c.checkStmt(stmt.Body)
+	})
+	return nil, nil
+}
+
+// loop checks a loop whose body runs for as long as condition, if there is
+// one, is true. Each iteration starts with the types the one before left,
+// so a first pass, reporting nothing, finds those, and the body is then
+// checked starting from either them or the types before the loop.
+func (c *Checker) loop(condition ast.Expr, body func()) {
+	reporting := c.reporting
+	c.reporting = false
+	first := c.branch(nil, func() { c.iteration(condition, body) })
+	c.reporting = reporting
+
+	c.beginScope()
+	c.join(nil, first)
+	again := c.branch(nil, func() { c.iteration(condition, body) })
+	c.join(nil, again)
+	if condition != nil {
+		c.apply(c.facts(condition, false))
+	}
+	c.restore(c.endBranch())
+}
+
+func (c *Checker) iteration(condition ast.Expr, body func()) {
+	if condition != nil {
+		c.check(condition)
+		c.apply(c.facts(condition, true))
+	}
+	body()
+}
+
+func (c *Checker) VisitFunctionStmt(stmt *ast.FunctionStmt) (interface{}, error) {
+	c.checkDecorators(stmt.Decorators)
+	v := &variable{function: stmt}
+	if len(stmt.Decorators) > 0 || c.reassigned[stmt.Name.Offset] {
+		// A decorator or assignment may replace the function.
+		v.function = nil
+	}
+	c.declare(stmt.Name, v)
+	c.checkFunction(stmt, stmt.Name.Lexeme)
+	return nil, nil
+}
+
+func (c *Checker) checkDecorators(decorators []*ast.Decorator) {
+	for _, decorator := range decorators {
+		c.check(decorator.Expression)
+	}
+}
+
+func (c *Checker) checkFunction(stmt *ast.FunctionStmt, name string) {
+	enclosing := c.function
+	c.function = &function{
+		name:       name,
+		returnType: c.typeOf(stmt.ReturnType),
+		annotated:  stmt.ReturnType != nil,
+	}
+	defer func() { c.function = enclosing }()
+
+	c.beginScope()
+	for n, param := range stmt.Params {
+		v := &variable{}
+		if n < len(stmt.ParamTypes) && stmt.ParamTypes[n] != nil {
+			v.declared = c.typeOf(stmt.ParamTypes[n])
+			v.current = v.declared
+			v.annotated = true
+		}
+		c.declare(param, v)
+	}
+	for _, clause := range stmt.Requires {
+		c.checkAssertion(clause)
+	}
+	if len(stmt.Ensures) > 0 {
+		c.beginScope()
+		result := c.function.returnType
+		c.scopes[len(c.scopes)-1]["result"] = &variable{
+			declared:  result,
+			annotated: c.function.annotated,
+			current:   result,
+		}
+		for _, clause := range stmt.Ensures {
+			c.checkAssertion(clause)
+		}
+		c.endScope()
+	}
+	c.hoist(stmt.Body)
+	c.checkStatements(stmt.Body)
+	c.endScope()
+
+	returnType := c.function.returnType
+	if c.function.annotated && !returnType.unknown() && !returnType.has(nilType) && !exitsBlock(stmt.Body) {
+		c.error(stmt.Name, errors.CodeTypeError, fmt.Sprintf(
+			"'%s' can reach the end without returning %s.", name, returnType))
+	}
+}
+
+func (c *Checker) VisitReturnStmt(stmt *ast.ReturnStmt) (interface{}, error) {
+	value := newType(false, nilType)
+	if stmt.Value != nil {
+		value = c.check(stmt.Value)
+	}
+	if c.function != nil && c.function.annotated && !c.assignable(value, c.function.returnType) {
+		c.error(stmt.Keyword, errors.CodeTypeError, fmt.Sprintf(
+			"Can't return %s from '%s', which returns %s.", value, c.function.name, c.function.returnType))
+	}
+	return nil, nil
+}
+
+func (c *Checker) VisitClassStmt(stmt *ast.ClassStmt) (interface{}, error) {
+	cls := c.declareClass(stmt)
+	v := c.lookup(stmt.Name.Lexeme)
+	if v == nil || v.class != cls {
+		c.declare(stmt.Name, &variable{class: cls})
+	}
+	if len(stmt.Decorators) > 0 || c.reassigned[stmt.Name.Offset] {
+		c.lookup(stmt.Name.Lexeme).class = nil
+	}
+
+	c.checkDecorators(stmt.Decorators)
+	if stmt.Superclass != nil {
+		c.check(stmt.Superclass)
+		cls.superclass = c.classes[stmt.Superclass.Name.Lexeme]
+	}
+	for _, field := range stmt.Fields {
+		cls.fields[field.Name.Lexeme] = c.typeOf(field.Type)
+	}
+	for _, method := range stmt.Methods {
+		c.checkDecorators(method.Decorators)
+		if len(method.Decorators) == 0 {
+			cls.methods[method.Name.Lexeme] = method
+		}
+	}
+
+	enclosing := c.class
+	c.class = cls
+	for _, method := range stmt.Methods {
+		c.checkFunction(method, cls.name+"."+method.Name.Lexeme)
+	}
+	c.class = enclosing
+	return nil, nil
+}
+
+func (c *Checker) VisitMatchStmt(stmt *ast.MatchStmt) (interface{}, error) {
+	c.checkMatch(stmt.Subject, stmt.Cases)
+	return nil, nil
+}
+
+func (c *Checker) VisitMatchExpr(expr *ast.Match) (interface{}, error) {
+	c.checkMatch(expr.Subject, expr.Cases)
+	return Type{}, nil
+}
+
+func (c *Checker) checkMatch(subject ast.Expr, cases []*ast.MatchCase) {
+	c.check(subject)
+	// No case may match, so the types before the match are one path out.
+	paths := []map[string]*variable{nil}
+	for _, matchCase := range cases {
+		path := c.branch(nil, func() {
+			for _, pattern := range matchCase.Patterns {
+				c.declarePattern(pattern)
+			}
+			if matchCase.Guard != nil {
+				c.check(matchCase.Guard)
+			}
+			if matchCase.Body != nil {
+				c.checkStmt(matchCase.Body)
+			} else {
+				c.check(matchCase.Value)
+			}
+		})
+		if matchCase.Body == nil || !exits(matchCase.Body) {
+			paths = append(paths, path)
+		}
+	}
+	c.join(paths...)
+}
+
+func (c *Checker) declarePattern(pattern ast.Pattern) {
+	switch pat := pattern.(type) {
+	case *ast.BindingPattern:
+		c.declare(pat.Name, &variable{})
+	case *ast.ClassPattern:
+		for _, field := range pat.Fields {
+			c.declarePattern(field)
+		}
+	}
+}
+
+func (c *Checker) VisitDestructureVarStmt(stmt *ast.DestructureVarStmt) (interface{}, error) {
+	c.check(stmt.Initializer)
+	for _, name := range stmt.Names {
+		c.declare(name, &variable{})
+	}
+	if stmt.Rest != nil {
+		c.declare(*stmt.Rest, &variable{})
+	}
+	return nil, nil
+}
+
+func (c *Checker) VisitSelectStmt(stmt *ast.SelectStmt) (interface{}, error) {
+	var paths []map[string]*variable
+	for _, selectCase := range stmt.Cases {
+		c.check(selectCase.Channel)
+		if selectCase.Value != nil {
+			c.check(selectCase.Value)
+		}
+		path := c.branch(nil, func() {
+			if selectCase.Name != nil {
+				c.declare(*selectCase.Name, &variable{})
+			}
+			c.checkStmt(selectCase.Body)
+		})
+		if !exits(selectCase.Body) {
+			paths = append(paths, path)
+		}
+	}
+	if stmt.Default != nil {
+		path := c.branch(nil, func() { c.checkStmt(stmt.Default) })
+		if !exits(stmt.Default) {
+			paths = append(paths, path)
+		}
+	}
+	c.join(paths...)
+	return nil, nil
+}
+
+func (c *Checker) VisitBinaryExpr(expr *ast.Binary) (interface{}, error) {
+	left := c.check(expr.Left)
+	right := c.check(expr.Right)
+	annotated := left.annotated || right.annotated
+	operator := expr.Operator
+
+	switch operator.Type {
+	case scanner.EQUAL_EQUAL, scanner.BANG_EQUAL:
+		return newType(annotated, boolType), nil
+	case scanner.PLUS:
+		if annotated && !addable(left, right) {
+			c.error(operator, errors.CodeTypeError, fmt.Sprintf(
+				"Operator '+' expects two numbers or two strings, not %s and %s.", left, right))
+		}
+		switch {
+		case left.is(numType) || right.is(numType):
+			return newType(annotated, numType), nil
+		case left.is(strType) || right.is(strType):
+			return newType(annotated, strType), nil
+		}
+		return Type{}, nil
+	}
+
+	for _, operand := range []Type{left, right} {
+		if annotated && !operand.unknown() && !operand.is(numType) {
+			c.error(operator, errors.CodeTypeError, fmt.Sprintf(
+				"Operator '%s' expects numbers, not %s.", operator.Lexeme, operand))
+			break
+		}
+	}
+	switch operator.Type {
+	case scanner.GREATER, scanner.GREATER_EQUAL, scanner.LESS, scanner.LESS_EQUAL:
+		return newType(annotated, boolType), nil
+	}
+	return newType(annotated, numType), nil
+}
+
+// addable reports whether '+' can apply to operands of types left and right:
+// both numbers or both strings.
+func addable(left, right Type) bool {
+	for _, operand := range []Type{left, right} {
+		if !operand.unknown() && !operand.is(numType) && !operand.is(strType) {
+			return false
+		}
+	}
+	if left.unknown() || right.unknown() {
+		return true
+	}
+	return left.is(right.names[0])
+}
+
+func (c *Checker) VisitGroupingExpr(expr *ast.Grouping) (interface{}, error) {
+	return c.check(expr.Expression), nil
+}
+
+func (c *Checker) VisitLiteralExpr(expr *ast.Literal) (interface{}, error) {
+	switch expr.Value.(type) {
+	case nil:
+		return newType(false, nilType), nil
+	case float64:
+		return newType(false, numType), nil
+	case string:
+		return newType(false, strType), nil
+	case bool:
+		return newType(false, boolType), nil
+	}
+	return Type{}, nil
+}
+
+func (c *Checker) VisitUnaryExpr(expr *ast.Unary) (interface{}, error) {
+	right := c.check(expr.Right)
+	if expr.Operator.Type == scanner.BANG {
+		return newType(right.annotated, boolType), nil
+	}
+	if right.annotated && !right.unknown() && !right.is(numType) {
+		c.error(expr.Operator, errors.CodeTypeError, fmt.Sprintf(
+			"Operator '%s' expects a number, not %s.", expr.Operator.Lexeme, right))
+	}
+	return newType(right.annotated, numType), nil
+}
+
+func (c *Checker) VisitLogicalExpr(expr *ast.Logical) (interface{}, error) {
+	left := c.check(expr.Left)
+	// The right operand only runs when the left one is true for 'and', or
+	// false for 'or'.
+	var right Type
+	path := c.branch(c.facts(expr.Left, expr.Operator.Type == scanner.AND), func() { right = c.check(expr.Right) })
+	c.join(nil, path)
+	return union(left, right), nil
+}
+
+func (c *Checker) VisitVariableExpr(expr *ast.Variable) (interface{}, error) {
+	if v := c.lookup(expr.Name.Lexeme); v != nil {
+		return v.current, nil
+	}
+	return Type{}, nil
+}
+
+func (c *Checker) VisitAssignExpr(expr *ast.Assign) (interface{}, error) {
+	value := c.check(expr.Value)
+	v := c.lookup(expr.Name.Lexeme)
+	if v == nil {
+		return value, nil
+	}
+	c.reassigned[v.key] = true
+	if v.annotated {
+		c.checkAssignment(expr.Name, value, v.declared, fmt.Sprintf("variable '%s'", expr.Name.Lexeme))
+		// Until it changes again, the variable has the type assigned.
+		current := v.declared
+		if !current.unknown() && !value.unknown() && c.assignable(value, v.declared) {
+			current = newType(true, value.names...)
+		}
+		c.narrow(expr.Name.Lexeme, current)
+	}
+	return value, nil
+}
+
+func (c *Checker) VisitDestructureAssignExpr(expr *ast.DestructureAssign) (interface{}, error) {
+	c.check(expr.Value)
+	for _, target := range expr.Targets {
+		if v := c.lookup(target.Name.Lexeme); v != nil {
+			c.reassigned[v.key] = true
+			if v.annotated {
+				c.narrow(target.Name.Lexeme, v.declared)
+			}
+		}
+	}
+	return Type{}, nil
+}
+
+func (c *Checker) VisitCallExpr(expr *ast.Call) (interface{}, error) {
+	// A method call's object is checked here rather than through
+	// VisitGetExpr, to find the method's class.
+	var object *class
+	if get, ok := expr.Callee.(*ast.Get); ok {
+		object = c.classOf(c.check(get.Object))
+	} else {
+		c.check(expr.Callee)
+	}
+	arguments := make([]Type, len(expr.Arguments))
+	for n, argument := range expr.Arguments {
+		arguments[n] = c.check(argument)
+	}
+
+	switch target := expr.Callee.(type) {
+	case *ast.Variable:
+		v := c.lookup(target.Name.Lexeme)
+		if v == nil || c.reassigned[v.key] {
+			return Type{}, nil
+		}
+		if v.function != nil {
+			return c.checkArguments(expr, v.function, arguments), nil
+		}
+		if v.class != nil {
+			if init := v.class.findMethod("init"); init != nil {
+				c.checkArguments(expr, init, arguments)
+			}
+			return newType(false, v.class.name), nil
+		}
+	case *ast.Get:
+		if object != nil {
+			if _, isField := object.findField(target.Name.Lexeme); !isField {
+				if method := object.findMethod(target.Name.Lexeme); method != nil {
+					return c.checkArguments(expr, method, arguments), nil
+				}
+			}
+		}
+	case *ast.Super:
+		if c.class != nil && c.class.superclass != nil {
+			if method := c.class.superclass.findMethod(target.Method.Lexeme); method != nil {
+				return c.checkArguments(expr, method, arguments), nil
+			}
+		}
+	}
+	return Type{}, nil
+}
+
+func (c *Checker) VisitGetExpr(expr *ast.Get) (interface{}, error) {
+	if cls := c.classOf(c.check(expr.Object)); cls != nil {
+		if field, ok := cls.findField(expr.Name.Lexeme); ok {
+			return field, nil
+		}
+	}
+	return Type{}, nil
+}
+
+func (c *Checker) VisitSetExpr(expr *ast.Set) (interface{}, error) {
+	value := c.check(expr.Value)
+	if cls := c.classOf(c.check(expr.Object)); cls != nil {
+		if field, ok := cls.findField(expr.Name.Lexeme); ok {
+			c.checkAssignment(expr.Name, value, field, fmt.Sprintf("field '%s'", expr.Name.Lexeme))
+		}
+	}
+	return value, nil
+}
+
+func (c *Checker) VisitThisExpr(expr *ast.This) (interface{}, error) {
+	if c.class == nil {
+		return Type{}, nil
+	}
+	return newType(false, c.class.name), nil
+}
+
+func (c *Checker) VisitSuperExpr(expr *ast.Super) (interface{}, error) {
+	return Type{}, nil
+}
+
+func (c *Checker) VisitListExpr(expr *ast.List) (interface{}, error) {
+	for _, element := range expr.Elements {
+		c.check(element)
+	}
+	return newType(false, listType), nil
+}
+
+func (c *Checker) VisitTupleExpr(expr *ast.Tuple) (interface{}, error) {
+	for _, element := range expr.Elements {
+		c.check(element)
+	}
+	return newType(false, tupleType), nil
+}
+
+func (c *Checker) VisitIndexExpr(expr *ast.Index) (interface{}, error) {
+	c.check(expr.Object)
+	c.check(expr.Index)
+	return Type{}, nil
+}
+
+func (c *Checker) VisitIndexSetExpr(expr *ast.IndexSet) (interface{}, error) {
+	c.check(expr.Object)
+	c.check(expr.Index)
+	return c.check(expr.Value), nil
+}
+
+func (c *Checker) VisitSpawnExpr(expr *ast.Spawn) (interface{}, error) {
+	c.check(expr.Call)
+	return Type{}, nil
+}
+
+func (c *Checker) VisitAwaitExpr(expr *ast.Await) (interface{}, error) {
+	c.check(expr.Value)
+	return Type{}, nil
+}
+
+var _ ast.ExprVisitor = &Checker{}
+var _ ast.StmtVisitor = &Checker{}
@@ -0,0 +1,108 @@
+package typecheck
+
+import (
+	"sort"
+	"strings"
+)
+
+// Names of the built-in types that annotations can use, besides class
+// names. 'any' is accepted too, and means the same as no annotation.
+const (
+	numType   = "num"
+	strType   = "str"
+	boolType  = "bool"
+	nilType   = "nil"
+	listType  = "list"
+	tupleType = "tuple"
+	anyType   = "any"
+)
+
+var builtinTypes = map[string]bool{
+	numType:   true,
+	strType:   true,
+	boolType:  true,
+	nilType:   true,
+	listType:  true,
+	tupleType: true,
+}
+
+// Type is a static type: a union of simple types, each a built-in type or a
+// class name. The zero Type is unknown, which the checker accepts anywhere;
+// unannotated code and 'any' have unknown types.
+type Type struct {
+	// names are sorted and distinct; none means unknown.
+	names []string
+	// annotated is set when the type comes from an annotation, directly or
+	// by inference from annotated code. Mismatches are only reported when
+	// an annotated type is involved, so unannotated code never gets errors.
+	annotated bool
+}
+
+// newType returns the union of names.
+func newType(annotated bool, names ...string) Type {
+	seen := make(map[string]bool)
+	var distinct []string
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			distinct = append(distinct, name)
+		}
+	}
+	sort.Strings(distinct)
+	return Type{names: distinct, annotated: annotated}
+}
+
+func (t Type) unknown() bool {
+	return len(t.names) == 0
+}
+
+// is reports whether t is known to be exactly name.
+func (t Type) is(name string) bool {
+	return len(t.names) == 1 && t.names[0] == name
+}
+
+func (t Type) has(name string) bool {
+	for _, n := range t.names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// without returns t with name left out of the union.
+func (t Type) without(name string) Type {
+	var names []string
+	for _, n := range t.names {
+		if n != name {
+			names = append(names, n)
+		}
+	}
+	return newType(t.annotated, names...)
+}
+
+// falsy returns the part of t that Lox treats as false: nil and booleans.
+func (t Type) falsy() Type {
+	var names []string
+	for _, n := range t.names {
+		if n == nilType || n == boolType {
+			names = append(names, n)
+		}
+	}
+	return newType(t.annotated, names...)
+}
+
+// union returns a type that covers values of both a and b.
+func union(a, b Type) Type {
+	if a.unknown() || b.unknown() {
+		return Type{}
+	}
+	return newType(a.annotated || b.annotated, append(append([]string(nil), a.names...), b.names...)...)
+}
+
+func (t Type) String() string {
+	if t.unknown() {
+		return anyType
+	}
+	return strings.Join(t.names, " | ")
+}